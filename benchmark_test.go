@@ -0,0 +1,90 @@
+// This file is part of go-trafilatura, Go package for extracting readable
+// content, comments and metadata from a web page. Source available in
+// <https://github.com/markusmobius/go-trafilatura>.
+// Copyright (C) 2021 Markus Mobius
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or (at your
+// option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+// or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License
+// for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package trafilatura
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-shiori/dom"
+	"golang.org/x/net/html"
+)
+
+// Each benchmark op extracts every mock page in test-files. Compare the
+// result before and after a change using `go test -run none -bench . -count 5`
+// along with benchstat.
+
+func BenchmarkExtract(b *testing.B) {
+	benchmarkExtract(b, Options{})
+}
+
+func BenchmarkExtractNoFallback(b *testing.B) {
+	benchmarkExtract(b, Options{NoFallback: true})
+}
+
+func BenchmarkExtractDocument(b *testing.B) {
+	var docs []*html.Node
+	for _, content := range benchmarkPages(b) {
+		doc, err := dom.Parse(bytes.NewReader(content))
+		if err != nil {
+			b.Fatal(err)
+		}
+		docs = append(docs, doc)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, doc := range docs {
+			ExtractDocument(doc, Options{})
+		}
+	}
+}
+
+func benchmarkExtract(b *testing.B, opts Options) {
+	pages := benchmarkPages(b)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, content := range pages {
+			Extract(bytes.NewReader(content), opts)
+		}
+	}
+}
+
+// benchmarkPages loads the content of mock pages in test-files.
+func benchmarkPages(b *testing.B) [][]byte {
+	paths, err := filepath.Glob(filepath.Join("test-files", "mock", "*.html"))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	var pages [][]byte
+	for _, path := range paths {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			b.Fatal(err)
+		}
+		pages = append(pages, content)
+	}
+	return pages
+}
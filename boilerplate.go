@@ -0,0 +1,187 @@
+// This file is part of go-trafilatura, Go package for extracting readable
+// content, comments and metadata from a web page. Source available in
+// <https://github.com/markusmobius/go-trafilatura>.
+// Copyright (C) 2021 Markus Mobius
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or (at your
+// option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+// or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License
+// for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package trafilatura
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/abadojack/whatlanggo"
+	"github.com/go-shiori/dom"
+	"golang.org/x/net/html"
+)
+
+// defaultBoilerplatePhrases is the built-in phrases for boilerplate text like share
+// and print buttons, grouped by ISO 639-1 language code. Phrases with empty language
+// code are brand names that used regardless of the page language.
+var defaultBoilerplatePhrases = map[string][]string{
+	"": {"E-Mail", "Facebook", "Flipboard", "Google", "Instagram", "Linkedin", "Mail",
+		"PDF", "Pinterest", "Pocket", "Reddit", "Twitter", "Whatsapp", "Xing"},
+	"ar": {"مشاركة", "طباعة"},
+	"de": {"Drucken", "Teilen"},
+	"en": {"Print", "Share"},
+	"es": {"Compartir", "Imprimir"},
+	"fr": {"Partager", "Imprimer"},
+	"id": {"Bagikan", "Cetak"},
+	"it": {"Condividi", "Stampa"},
+	"ja": {"シェア", "ツイート", "印刷"},
+	"ko": {"공유", "공유하기", "인쇄"},
+	"nl": {"Delen", "Afdrukken"},
+	"pl": {"Udostępnij", "Drukuj"},
+	"pt": {"Compartilhar", "Partilhar", "Imprimir"},
+	"ru": {"Поделиться", "Печать"},
+	"tr": {"Paylaş", "Yazdır"},
+	"zh": {"分享", "打印", "列印"},
+}
+
+// fallbackBoilerplateLanguages is the languages whose phrases are used when the page
+// language is unknown, which matches the phrases that filtered before localization.
+var fallbackBoilerplateLanguages = []string{"en", "de"}
+
+// rxDefaultBoilerplates caches the compiled filter of built-in phrases for each language.
+var rxDefaultBoilerplates sync.Map
+
+// DefaultBoilerplatePhrases returns copy of the built-in boilerplate phrases, which
+// can be extended and then used in `Options.BoilerplatePhrases`.
+func DefaultBoilerplatePhrases() map[string][]string {
+	phrases := make(map[string][]string, len(defaultBoilerplatePhrases))
+	for lang, list := range defaultBoilerplatePhrases {
+		phrases[lang] = append([]string{}, list...)
+	}
+	return phrases
+}
+
+// boilerplateFilter returns regex for filtering the boilerplate text in the page. The
+// phrases are selected using the language that declared in the page, the target
+// language or the language that detected from the page paragraphs, in that order. If
+// the language is still unknown, the English and German phrases are used.
+func boilerplateFilter(doc *html.Node, opts Options) *regexp.Regexp {
+	lang := htmlLanguage(doc)
+	if lang == "" {
+		lang = strings.ToLower(opts.TargetLanguage)
+	}
+	if lang == "" {
+		lang = detectPageLanguage(doc)
+	}
+
+	if opts.BoilerplatePhrases != nil {
+		return compileBoilerplateFilter(opts.BoilerplatePhrases, lang)
+	}
+
+	if rx, exist := rxDefaultBoilerplates.Load(lang); exist {
+		return rx.(*regexp.Regexp)
+	}
+
+	rx := compileBoilerplateFilter(defaultBoilerplatePhrases, lang)
+	rxDefaultBoilerplates.Store(lang, rx)
+	return rx
+}
+
+// compileBoilerplateFilter creates regex for filtering the boilerplate text. Since the
+// language-neutral phrases are mostly brand names, they are matched at the end of line.
+// Meanwhile the phrases for the specified language are common words, so they must fill
+// the entire line except for the punctuation.
+func compileBoilerplateFilter(phrases map[string][]string, lang string) *regexp.Regexp {
+	var patterns []string
+	if neutral := quotePhrases(phrases[""]); neutral != "" {
+		patterns = append(patterns, `[^\p{L}\p{N}]*(`+neutral+`)$`)
+	}
+
+	languages := []string{lang}
+	if lang == "" {
+		languages = fallbackBoilerplateLanguages
+	}
+
+	var localizedPhrases []string
+	for _, language := range languages {
+		localizedPhrases = append(localizedPhrases, phrases[language]...)
+	}
+
+	if localized := quotePhrases(localizedPhrases); localized != "" {
+		patterns = append(patterns, `^[^\p{L}\p{N}]*(`+localized+`)[^\p{L}\p{N}]*$`)
+	}
+
+	if len(patterns) == 0 {
+		return nil
+	}
+
+	return regexp.MustCompile(`(?i)` + strings.Join(patterns, "|"))
+}
+
+// quotePhrases converts the phrases into regex alternation.
+func quotePhrases(phrases []string) string {
+	var quoted []string
+	for _, phrase := range phrases {
+		if phrase = strings.TrimSpace(phrase); phrase != "" {
+			quoted = append(quoted, regexp.QuoteMeta(phrase))
+		}
+	}
+	return strings.Join(quoted, "|")
+}
+
+// htmlLanguage returns the primary language that declared in HTML lang attribute
+// or in content-language meta tag.
+func htmlLanguage(doc *html.Node) string {
+	htmlNode := doc
+	if dom.TagName(htmlNode) != "html" {
+		htmlNode = dom.QuerySelector(doc, "html")
+	}
+
+	var declared string
+	if htmlNode != nil && dom.HasAttribute(htmlNode, "lang") {
+		declared = dom.GetAttribute(htmlNode, "lang")
+	} else if metaNode := dom.QuerySelector(doc, `meta[http-equiv="content-language"]`); metaNode != nil {
+		declared = dom.GetAttribute(metaNode, "content")
+	}
+
+	if match := rxHtmlLang.FindStringSubmatch(declared); len(match) > 1 {
+		return strings.ToLower(match[1])
+	}
+	return ""
+}
+
+// detectPageLanguage detects the language of the page using the text of its paragraphs.
+func detectPageLanguage(doc *html.Node) string {
+	var sb strings.Builder
+	var nChars int
+	for _, p := range dom.GetElementsByTagName(doc, "p") {
+		text := trim(dom.TextContent(p))
+		if text == "" {
+			continue
+		}
+
+		sb.WriteString(text)
+		sb.WriteString(" ")
+		if nChars += utf8.RuneCountInString(text); nChars > 1000 {
+			break
+		}
+	}
+
+	if sb.Len() == 0 {
+		return ""
+	}
+
+	info := whatlanggo.Detect(sb.String())
+	if !info.IsReliable() {
+		return ""
+	}
+	return info.Lang.Iso6391()
+}
@@ -0,0 +1,88 @@
+// This file is part of go-trafilatura, Go package for extracting readable
+// content, comments and metadata from a web page. Source available in
+// <https://github.com/markusmobius/go-trafilatura>.
+// Copyright (C) 2021 Markus Mobius
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or (at your
+// option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+// or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License
+// for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package trafilatura
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-shiori/dom"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_BoilerplateFilter(t *testing.T) {
+	// Helper function
+	extractWithLine := func(lang, line string, opts Options) string {
+		paragraph := "<p>" + strings.Repeat("Lorem ipsum dolor sit amet, consectetur adipiscing elit. ", 5) + "</p>"
+		rawHTML := `<html lang="` + lang + `"><body><article>` + paragraph + "<p>" + line + "</p>" +
+			paragraph + "</article></body></html>"
+
+		result, err := Extract(strings.NewReader(rawHTML), opts)
+		assert.Nil(t, err)
+		return result.ContentText
+	}
+
+	// Phrases are chosen by the declared language
+	opts := Options{NoFallback: true, Config: DefaultConfig()}
+	assert.NotContains(t, extractWithLine("fr", "Partager", opts), "Partager")
+	assert.Contains(t, extractWithLine("en", "Partager", opts), "Partager")
+	assert.NotContains(t, extractWithLine("es-MX", "» Compartir", opts), "Compartir")
+	assert.NotContains(t, extractWithLine("ja", "シェア", opts), "シェア")
+	assert.NotContains(t, extractWithLine("id", "Bagikan", opts), "Bagikan")
+
+	// Language-neutral phrases are used for every page
+	assert.NotContains(t, extractWithLine("fr", "« Twitter", opts), "Twitter")
+
+	// Custom phrases
+	opts.BoilerplatePhrases = DefaultBoilerplatePhrases()
+	opts.BoilerplatePhrases["fr"] = append(opts.BoilerplatePhrases["fr"], "Réagir")
+	assert.NotContains(t, extractWithLine("fr", "Réagir", opts), "Réagir")
+	assert.NotContains(t, defaultBoilerplatePhrases["fr"], "Réagir")
+
+	opts.BoilerplatePhrases = map[string][]string{}
+	assert.Contains(t, extractWithLine("fr", "Twitter", opts), "Twitter")
+
+	// Phrases are chosen by the target language if language is not declared
+	opts = Options{TargetLanguage: "fr"}
+	doc := docFromStr(`<html><body><p>Bonjour</p></body></html>`)
+	assert.True(t, boilerplateFilter(doc, opts).MatchString("Partager"))
+
+	// Phrases are chosen by the detected language
+	opts = Options{}
+	doc = docFromStr(`<html><body><p>Le gouvernement a annoncé mardi une série de mesures pour soutenir les
+		agriculteurs touchés par la sécheresse, notamment des aides financières et des reports de charges.</p>
+		<p>Partager</p></body></html>`)
+	assert.True(t, boilerplateFilter(doc, opts).MatchString("Partager"))
+	assert.False(t, boilerplateFilter(doc, opts).MatchString("Compartir"))
+
+	// English and German phrases are used if the language is unknown
+	doc = docFromStr(`<html><body><p>Print</p></body></html>`)
+	assert.True(t, boilerplateFilter(doc, opts).MatchString("Print"))
+	assert.True(t, boilerplateFilter(doc, opts).MatchString("Drucken"))
+	assert.False(t, boilerplateFilter(doc, opts).MatchString("Partager"))
+}
+
+func Test_HtmlLanguage(t *testing.T) {
+	assert.Equal(t, "", htmlLanguage(docFromStr(`<html><body></body></html>`)))
+	assert.Equal(t, "pt", htmlLanguage(docFromStr(`<html lang="pt-BR"><body></body></html>`)))
+	assert.Equal(t, "de", htmlLanguage(docFromStr(`<html><head><meta http-equiv="content-language" content="de_DE, en_US"></head><body></body></html>`)))
+
+	doc := docFromStr(`<html lang="ZH-Hant"><body><p>Test</p></body></html>`)
+	assert.Equal(t, "zh", htmlLanguage(dom.QuerySelector(doc, "html")))
+}
@@ -0,0 +1,302 @@
+// This file is part of go-trafilatura, Go package for extracting readable
+// content, comments and metadata from a web page. Source available in
+// <https://github.com/markusmobius/go-trafilatura>.
+// Copyright (C) 2021 Markus Mobius
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or (at your
+// option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+// or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License
+// for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package trafilatura
+
+import (
+	"strings"
+)
+
+const defaultChunkSize = 1000
+
+// ChunkOptions is configuration for splitting the extracted content into chunks.
+type ChunkOptions struct {
+	// MaxSize is the max size of each chunk. If it's zero or negative, 1000 is used.
+	// Block that bigger than this size is split at line, then word boundary.
+	MaxSize int
+
+	// Overlap is the size of text from the end of previous chunk which repeated
+	// at the start of the next chunk in the same section. It's capped at half
+	// of the max size.
+	Overlap int
+
+	// CountTokens specify whether the size is measured in approximate number of
+	// tokens instead of characters. A token is counted as four characters, except
+	// Chinese, Japanese and Korean character which counted as one token each.
+	CountTokens bool
+}
+
+// Chunk is a part of extracted content, e.g. to be used for embedding.
+type Chunk struct {
+	// Index is the position of chunk in the content, starting from zero.
+	Index int
+
+	// Text is the content of chunk, rendered as plain text.
+	Text string
+
+	// Size is the size of the text, in characters or tokens following the options.
+	Size int
+
+	// Headings is the titles of the sections where the chunk belongs,
+	// from the top level section until the nearest one.
+	Headings []string
+
+	// Metadata is the metadata of the page.
+	Metadata Metadata
+}
+
+// Chunks splits the extracted content into chunks which size is not bigger than the
+// max size, if possible. The chunks respect the block boundaries: paragraph, list,
+// table and code are only split if it's too big, and each heading starts a new chunk.
+func (r *ExtractResult) Chunks(opts ChunkOptions) []Chunk {
+	if r == nil || r.ContentNode == nil {
+		return nil
+	}
+
+	maxSize := opts.MaxSize
+	if maxSize <= 0 {
+		maxSize = defaultChunkSize
+	}
+
+	overlap := opts.Overlap
+	if overlap < 0 {
+		overlap = 0
+	} else if overlap > maxSize/2 {
+		overlap = maxSize / 2
+	}
+
+	chunker := chunker{
+		maxSize:     maxSize,
+		overlap:     overlap,
+		countTokens: opts.CountTokens,
+		metadata:    r.Metadata,
+	}
+
+	chunker.addSections(r.Sections(), nil)
+	return chunker.chunks
+}
+
+// chunker collects the blocks of content into chunks.
+type chunker struct {
+	maxSize     int
+	overlap     int
+	countTokens bool
+	metadata    Metadata
+
+	chunks     []Chunk
+	headings   []string
+	text       string
+	hasContent bool
+}
+
+// chunkPiece is a part of block, along with the separator that used to join it
+// with the previous piece.
+type chunkPiece struct {
+	text      string
+	separator string
+}
+
+// addSections adds the content of sections and their children.
+func (c *chunker) addSections(sections []*Section, headings []string) {
+	for _, section := range sections {
+		sectionHeadings := headings
+		if section.Heading != nil {
+			sectionHeadings = append(append([]string{}, headings...), section.Title)
+		}
+
+		// Each section starts a new chunk
+		c.flush(false)
+		c.headings = sectionHeadings
+
+		for _, node := range section.Nodes {
+			block := RenderText(node, TextOptions{})
+			for _, piece := range c.split(block, "\n\n") {
+				c.add(piece)
+			}
+		}
+
+		c.addSections(section.Children, sectionHeadings)
+	}
+
+	c.flush(false)
+}
+
+// add puts the piece into current chunk, or starts a new one if it doesn't fit.
+func (c *chunker) add(piece chunkPiece) {
+	if c.hasContent && c.textSize(c.text+piece.separator+piece.text) > c.maxSize {
+		c.flush(true)
+	}
+
+	switch {
+	case c.text == "":
+		c.text = piece.text
+	case c.textSize(c.text+piece.separator+piece.text) > c.maxSize:
+		// Drop the overlap since there are no room for it
+		c.text = piece.text
+	default:
+		c.text += piece.separator + piece.text
+	}
+
+	c.hasContent = true
+}
+
+// flush saves the current chunk. If keepOverlap is true, the end of current
+// chunk is kept as the start of the next chunk.
+func (c *chunker) flush(keepOverlap bool) {
+	text := c.text
+	hasContent := c.hasContent
+	c.text, c.hasContent = "", false
+
+	// Chunk that only contains overlap is not saved
+	if !hasContent {
+		return
+	}
+
+	c.chunks = append(c.chunks, Chunk{
+		Index:    len(c.chunks),
+		Text:     text,
+		Size:     c.textSize(text),
+		Headings: c.headings,
+		Metadata: c.metadata,
+	})
+
+	if keepOverlap && c.overlap > 0 {
+		c.text = c.overlapText(text)
+	}
+}
+
+// overlapText returns the last words in text which size is within the overlap size.
+func (c *chunker) overlapText(text string) string {
+	words := strings.Fields(text)
+	start := len(words)
+	for start > 0 && c.textSize(strings.Join(words[start-1:], " ")) <= c.overlap {
+		start--
+	}
+	return strings.Join(words[start:], " ")
+}
+
+// split splits the block that bigger than the max size, first at blank lines,
+// then at line breaks and finally between words.
+func (c *chunker) split(block string, separator string) []chunkPiece {
+	if strings.TrimSpace(block) == "" {
+		return nil
+	}
+
+	if c.textSize(block) <= c.maxSize {
+		return []chunkPiece{{text: block, separator: separator}}
+	}
+
+	for _, partSeparator := range []string{"\n\n", "\n", " "} {
+		parts := strings.Split(block, partSeparator)
+		if len(parts) < 2 {
+			continue
+		}
+
+		var pieces []chunkPiece
+		var current string
+		currentSeparator := separator
+
+		for _, part := range parts {
+			if partSeparator == " " && part == "" {
+				continue
+			}
+
+			switch {
+			case current == "":
+				current = part
+			case c.textSize(current+partSeparator+part) <= c.maxSize:
+				current += partSeparator + part
+			default:
+				pieces = append(pieces, c.split(current, currentSeparator)...)
+				current, currentSeparator = part, partSeparator
+			}
+		}
+
+		return append(pieces, c.split(current, currentSeparator)...)
+	}
+
+	// Single word that bigger than max size, e.g. long URL or text without space
+	var pieces []chunkPiece
+	for i, part := range c.splitRunes(block) {
+		if i == 0 {
+			pieces = append(pieces, chunkPiece{text: part, separator: separator})
+		} else {
+			pieces = append(pieces, chunkPiece{text: part})
+		}
+	}
+
+	return pieces
+}
+
+// splitRunes splits the text by characters, as the last resort.
+func (c *chunker) splitRunes(text string) []string {
+	var results []string
+	var current []rune
+	var nChars, nCJK int
+
+	for _, r := range text {
+		if isCJK(r) {
+			nCJK++
+		} else {
+			nChars++
+		}
+
+		if len(current) > 0 && c.countSize(nChars, nCJK) > c.maxSize {
+			results = append(results, string(current))
+			current = nil
+			nChars, nCJK = 0, 0
+			if isCJK(r) {
+				nCJK++
+			} else {
+				nChars++
+			}
+		}
+
+		current = append(current, r)
+	}
+
+	if len(current) > 0 {
+		results = append(results, string(current))
+	}
+
+	return results
+}
+
+// textSize returns the size of text in characters or approximate tokens.
+func (c *chunker) textSize(text string) int {
+	var nChars, nCJK int
+	for _, r := range text {
+		if isCJK(r) {
+			nCJK++
+		} else {
+			nChars++
+		}
+	}
+
+	return c.countSize(nChars, nCJK)
+}
+
+// countSize returns the size in characters or approximate tokens from number of
+// CJK characters and other characters.
+func (c *chunker) countSize(nChars, nCJK int) int {
+	if !c.countTokens {
+		return nChars + nCJK
+	}
+
+	return (nChars+3)/4 + nCJK
+}
@@ -0,0 +1,108 @@
+// This file is part of go-trafilatura, Go package for extracting readable
+// content, comments and metadata from a web page. Source available in
+// <https://github.com/markusmobius/go-trafilatura>.
+// Copyright (C) 2021 Markus Mobius
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or (at your
+// option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+// or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License
+// for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package trafilatura
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/markusmobius/go-trafilatura/internal/etree"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Chunks(t *testing.T) {
+	// Helper function
+	chunkTexts := func(chunks []Chunk) []string {
+		var texts []string
+		for _, chunk := range chunks {
+			texts = append(texts, chunk.Text)
+		}
+		return texts
+	}
+
+	// Empty result
+	assert.Nil(t, (*ExtractResult)(nil).Chunks(ChunkOptions{}))
+	assert.Nil(t, (&ExtractResult{}).Chunks(ChunkOptions{}))
+
+	// Blocks are kept whole, and heading starts a new chunk
+	result := &ExtractResult{
+		Metadata: Metadata{Title: "Guide", URL: "https://example.org/guide"},
+		ContentNode: etree.FromString(`<article>
+			<p>Intro text.</p>
+			<h1>Setup</h1>
+			<p>First paragraph.</p>
+			<ul><li>Item one</li><li>Item two</li></ul>
+			<h2>Config</h2>
+			<table><tr><th>Key</th><th>Value</th></tr><tr><td>a</td><td>1</td></tr></table>
+			<pre><code>x := 1
+y := 2</code></pre>
+		</article>`),
+	}
+
+	chunks := result.Chunks(ChunkOptions{MaxSize: 40})
+	assert.Equal(t, []string{
+		"Intro text.",
+		"First paragraph.\n\n- Item one\n- Item two",
+		"Key | Value\n----+------\na   | 1",
+		"x := 1\ny := 2",
+	}, chunkTexts(chunks))
+
+	assert.Equal(t, 0, chunks[0].Index)
+	assert.Equal(t, 3, chunks[3].Index)
+	assert.Nil(t, chunks[0].Headings)
+	assert.Equal(t, []string{"Setup"}, chunks[1].Headings)
+	assert.Equal(t, []string{"Setup", "Config"}, chunks[2].Headings)
+	assert.Equal(t, "Guide", chunks[3].Metadata.Title)
+	assert.Equal(t, "https://example.org/guide", chunks[3].Metadata.URL)
+	assert.Equal(t, 11, chunks[0].Size)
+
+	// Big block is split at line then word boundary, with overlap
+	result.ContentNode = etree.FromString(`<article>
+		<p>one two three four five six seven eight nine ten</p>
+		<ul><li>first item</li><li>second item</li><li>third item</li></ul>
+	</article>`)
+
+	chunks = result.Chunks(ChunkOptions{MaxSize: 25})
+	assert.Equal(t, []string{
+		"one two three four five",
+		"six seven eight nine ten",
+		"- first item",
+		"- second item",
+		"- third item",
+	}, chunkTexts(chunks))
+
+	chunks = result.Chunks(ChunkOptions{MaxSize: 30, Overlap: 10})
+	assert.Equal(t, []string{
+		"one two three four five six",
+		"five six seven eight nine ten",
+		"- first item\n- second item",
+		"item\n- third item",
+	}, chunkTexts(chunks))
+
+	// Size in approximate tokens
+	result.ContentNode = etree.FromString(`<article><p>` + strings.Repeat("abcd ", 20) + `</p><p>東京都庁</p></article>`)
+	chunks = result.Chunks(ChunkOptions{MaxSize: 10, CountTokens: true})
+	assert.Equal(t, []string{
+		strings.TrimSpace(strings.Repeat("abcd ", 8)),
+		strings.TrimSpace(strings.Repeat("abcd ", 8)),
+		strings.TrimSpace(strings.Repeat("abcd ", 4)) + "\n\n東京都庁",
+	}, chunkTexts(chunks))
+	assert.Equal(t, 10, chunks[0].Size)
+	assert.Equal(t, 10, chunks[2].Size)
+}
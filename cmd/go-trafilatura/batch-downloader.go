@@ -20,12 +20,15 @@ package main
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	nurl "net/url"
+	"os"
 	"time"
 
 	"github.com/markusmobius/go-trafilatura"
 	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
 	"golang.org/x/sync/errgroup"
 	"golang.org/x/sync/semaphore"
 )
@@ -33,54 +36,420 @@ import (
 type batchDownloader struct {
 	extractOptions trafilatura.Options
 	semaphore      *semaphore.Weighted
+	hostLimiter    *hostLimiter
+	robotsChecker  *robotsChecker
+	state          *crawlState
+	httpCache      *httpCache
+	cacheOnly      bool
+	warcWriter     *warcWriter
+	jsonlWriter    *jsonlWriter
+	metrics        *trafilatura.PrometheusMetrics
+	metricsPath    string
 	httpClient     *http.Client
 	userAgent      string
-	delay          time.Duration
+	maxRetries     int
+	retryBackoff   time.Duration
 	cancelOnError  bool
-	writeFunc      func(*trafilatura.ExtractResult, *nurl.URL, int) error
+	writeFunc      func(*trafilatura.ExtractResult, *nurl.URL, int) (string, error)
+}
+
+// downloadResult is the result of processing a single URL in batch downloader.
+type downloadResult struct {
+	Page     *fetchedPage
+	Result   *trafilatura.ExtractResult
+	Attempts int
+}
+
+// addDownloaderFlags registers flags that used to configure batch downloader.
+func addDownloaderFlags(cmd *cobra.Command) {
+	flags := cmd.Flags()
+	flags.Int("parallel", 10, "number of concurrent download at a time (default 10)")
+	flags.Int("host-parallel", 0, "number of concurrent download at a time for each host (default unlimited)")
+	flags.Int("delay", 0, "delay between each url download to the same host in seconds (default 0)")
+	flags.Bool("robots", false, "respect robots.txt by skipping disallowed urls and honoring crawl-delay")
+	flags.String("robots-agent", defaultRobotsAgent, "product token for finding the rules that applied to us in robots.txt")
+	flags.Float64("host-rate", 0, "max number of requests per second for each host (default unlimited)")
+	flags.Int("host-burst", 1, "max number of requests for each host that allowed to exceed the rate, not applied to --delay (default 1)")
+	flags.Int("host-max-failures", 5, "number of consecutive failures before a host is paused (default 5)")
+	flags.Int("host-cooldown", 60, "how long a host is paused after too many failures in seconds (default 60)")
+	flags.Int("retries", 2, "number of retries for download that failed with network error, 429 or 5xx (default 2)")
+	flags.Int("backoff", 1, "initial delay before retrying failed download in seconds, doubled on each retry (default 1)")
+	flags.String("state", "", "path to JSONL file for saving crawl state, used to skip finished urls when resuming crawl")
+	flags.String("cache", "", "directory for HTTP cache, used to send conditional requests and skip unchanged pages")
+	flags.Bool("cache-body", false, "store raw HTML in HTTP cache, so the pages can be extracted again later")
+	flags.Bool("cache-only", false, "extract pages from raw HTML in HTTP cache without downloading them")
+	flags.String("warc", "", "path to WARC file for saving all downloaded responses, compressed if it ends with .gz")
+	flags.String("metrics", "", "path to file for saving extraction metrics in Prometheus text format")
+	flags.String("jsonl", "", "write all results as JSON Lines into this file instead of output dir, use '-' for stdout and .gz for compression")
+}
+
+// newBatchDownloader creates batch downloader using the flags from command.
+func newBatchDownloader(cmd *cobra.Command, httpClient *http.Client, writeFunc func(*trafilatura.ExtractResult, *nurl.URL, int) (string, error)) *batchDownloader {
+	flags := cmd.Flags()
+	delay, _ := flags.GetInt("delay")
+	nThread, _ := flags.GetInt("parallel")
+	nHostThread, _ := flags.GetInt("host-parallel")
+	hostRate, _ := flags.GetFloat64("host-rate")
+	hostBurst, _ := flags.GetInt("host-burst")
+	hostMaxFailures, _ := flags.GetInt("host-max-failures")
+	hostCooldown, _ := flags.GetInt("host-cooldown")
+	maxRetries, _ := flags.GetInt("retries")
+	backoff, _ := flags.GetInt("backoff")
+	respectRobots, _ := flags.GetBool("robots")
+	robotsAgent, _ := flags.GetString("robots-agent")
+	statePath, _ := flags.GetString("state")
+	cacheDir, _ := flags.GetString("cache")
+	cacheBody, _ := flags.GetBool("cache-body")
+	cacheOnly, _ := flags.GetBool("cache-only")
+	warcPath, _ := flags.GetString("warc")
+	jsonlPath, _ := flags.GetString("jsonl")
+	metricsPath, _ := flags.GetString("metrics")
+	userAgent, _ := flags.GetString("user-agent")
+
+	// Pages in cache are not downloaded, so there is no need to check robots.txt.
+	// Robots.txt is downloaded under the same limit as the pages.
+	sem := semaphore.NewWeighted(int64(nThread))
+
+	var checker *robotsChecker
+	if respectRobots && !cacheOnly {
+		checker = newRobotsChecker(httpClient, userAgent, robotsAgent, sem)
+	}
+
+	var state *crawlState
+	if statePath != "" {
+		var err error
+		state, err = openCrawlState(statePath)
+		if err != nil {
+			logrus.Fatalf("failed to open state file: %v", err)
+		}
+	}
+
+	var cache *httpCache
+	if cacheDir != "" {
+		var err error
+		cache, err = newHttpCache(cacheDir, cacheBody)
+		if err != nil {
+			logrus.Fatalf("failed to prepare cache dir: %v", err)
+		}
+	} else if cacheOnly {
+		logrus.Fatalf("cache dir must be specified when using --cache-only")
+	}
+
+	var warc *warcWriter
+	if warcPath != "" {
+		var err error
+		warc, err = newWarcWriter(warcPath)
+		if err != nil {
+			logrus.Fatalf("failed to create WARC file: %v", err)
+		}
+	}
+
+	var jsonl *jsonlWriter
+	if jsonlPath != "" {
+		var err error
+		jsonl, err = newJsonlWriter(jsonlPath)
+		if err != nil {
+			logrus.Fatalf("failed to create JSONL output: %v", err)
+		}
+	}
+
+	var metrics *trafilatura.PrometheusMetrics
+	extractOptions := createExtractorOptions(cmd)
+	if metricsPath != "" {
+		metrics = trafilatura.NewPrometheusMetrics()
+		extractOptions.Metrics = metrics
+	}
+
+	return &batchDownloader{
+		userAgent:      userAgent,
+		httpClient:     httpClient,
+		extractOptions: extractOptions,
+		metrics:        metrics,
+		metricsPath:    metricsPath,
+		semaphore:      sem,
+		robotsChecker:  checker,
+		state:          state,
+		httpCache:      cache,
+		cacheOnly:      cacheOnly,
+		warcWriter:     warc,
+		jsonlWriter:    jsonl,
+		maxRetries:     maxRetries,
+		retryBackoff:   time.Duration(backoff) * time.Second,
+		hostLimiter: newHostLimiter(hostLimiterConfig{
+			MaxConcurrent: nHostThread,
+			Rate:          hostRate,
+			Burst:         hostBurst,
+			Delay:         time.Duration(delay) * time.Second,
+			MaxFailures:   hostMaxFailures,
+			Cooldown:      time.Duration(hostCooldown) * time.Second,
+		}),
+		cancelOnError: false,
+		writeFunc:     writeFunc,
+	}
 }
 
 func (bd *batchDownloader) downloadURLs(ctx context.Context, urls []*nurl.URL) error {
-	g, ctx := errgroup.WithContext(context.Background())
+	g, ctx := errgroup.WithContext(ctx)
 
 	for i, url := range urls {
 		i, url := i, url
+		strURL := url.String()
 
 		g.Go(func() error {
-			// Acquire semaphore to limit concurrent download
-			err := bd.semaphore.Acquire(ctx, 1)
-			if err != nil {
+			// Skip URL that already processed in the previous run
+			if bd.state != nil && bd.state.isDone(strURL) {
+				logrus.Printf("skipping %s: already processed", strURL)
+				bd.state.markResumed()
 				return nil
 			}
 
+			// Make sure the URL is allowed by robots.txt
+			var crawlDelay time.Duration
+			if bd.robotsChecker != nil {
+				if !bd.robotsChecker.isAllowed(url) {
+					logrus.Warnf("skipping %s: disallowed by robots.txt", strURL)
+					bd.saveRecord(crawlRecord{
+						URL:    strURL,
+						Status: crawlStatusSkipped,
+						Error:  "disallowed by robots.txt",
+					}, nil)
+					return nil
+				}
+				crawlDelay = bd.robotsChecker.crawlDelay(url)
+			}
+
 			// Process URL
-			result, err := processURL(bd.httpClient, bd.userAgent, url, bd.extractOptions)
-			bd.semaphore.Release(1)
+			res, err := bd.processURL(ctx, url, crawlDelay)
+			record := crawlRecord{URL: strURL, Attempts: res.Attempts}
+			if res.Page != nil {
+				record.HTTPStatus = res.Page.StatusCode
+			}
+
+			if errors.Is(err, errNotModified) {
+				logrus.Printf("skipping %s: not modified", strURL)
+				record.Status = crawlStatusUnchanged
+				bd.saveRecord(record, nil)
+				return nil
+			}
+
 			if err != nil {
+				record.Status, record.Error = crawlStatusFailed, err.Error()
+				bd.saveRecord(record, nil)
+
 				if bd.cancelOnError {
 					return err
 				}
 
-				logrus.Warnf("failed to process %s: %v", url.String(), err)
+				logrus.Warnf("failed to process %s: %v", strURL, err)
+				return nil
+			}
+
+			// If results are streamed as JSON Lines, there is no need to write to file
+			if bd.jsonlWriter != nil {
+				record.Status = crawlStatusDone
+				bd.saveRecord(record, res.Result)
+				bd.cachePage(res.Page)
 				return nil
 			}
 
 			// Write to file
-			err = bd.writeFunc(result, url, i)
+			outputFile, err := bd.writeFunc(res.Result, url, i)
 			if err != nil {
+				record.Status, record.Error = crawlStatusFailed, err.Error()
+				bd.saveRecord(record, nil)
+
 				if bd.cancelOnError {
 					return err
 				}
 
-				logrus.Warnf("failed to write %s: %v", url.String(), err)
+				logrus.Warnf("failed to write %s: %v", strURL, err)
 				return nil
 			}
 
-			// Add delay (to prevent too many request to target server)
-			time.Sleep(bd.delay)
+			record.Status, record.OutputFile = crawlStatusDone, outputFile
+			bd.saveRecord(record, nil)
+			bd.cachePage(res.Page)
 			return nil
 		})
 	}
 
-	return g.Wait()
+	err := g.Wait()
+
+	// Close the output files, since batch downloader is only used once
+	bd.close()
+
+	// Save metrics of the extraction
+	if bd.metrics != nil {
+		if err := saveMetrics(bd.metricsPath, bd.metrics); err != nil {
+			logrus.Warnf("failed to save metrics: %v", err)
+		}
+	}
+
+	// Print summary of the crawl
+	if bd.state != nil {
+		bd.state.runSummary().writeText(os.Stderr)
+	}
+
+	return err
+}
+
+// close closes the WARC file, JSON Lines output and state file opened by batch downloader.
+func (bd *batchDownloader) close() {
+	if bd.warcWriter != nil {
+		if err := bd.warcWriter.Close(); err != nil {
+			logrus.Warnf("failed to close WARC file: %v", err)
+		}
+	}
+
+	if bd.jsonlWriter != nil {
+		if err := bd.jsonlWriter.Close(); err != nil {
+			logrus.Warnf("failed to close JSONL output: %v", err)
+		}
+	}
+
+	if bd.state != nil {
+		if err := bd.state.Close(); err != nil {
+			logrus.Warnf("failed to close state file: %v", err)
+		}
+	}
+}
+
+// processURL downloads and extracts the URL. If download failed because of temporary error,
+// it will be retried several times with exponential backoff.
+func (bd *batchDownloader) processURL(ctx context.Context, url *nurl.URL, crawlDelay time.Duration) (downloadResult, error) {
+	var res downloadResult
+	for attempt := 0; ; attempt++ {
+		var err error
+		res.Attempts++
+		res.Page, res.Result, err = bd.processURLOnce(ctx, url, crawlDelay)
+
+		retryable, retryAfter := isRetryableError(err)
+		if !retryable || attempt >= bd.maxRetries {
+			return res, err
+		}
+
+		wait := retryBackoff(bd.retryBackoff, attempt)
+		if retryAfter > wait {
+			wait = retryAfter
+		}
+
+		if wait > maxRetryWait {
+			wait = maxRetryWait
+		}
+
+		logrus.Warnf("failed to download %s, retrying in %v: %v", url.String(), wait, err)
+		if err := sleepContext(ctx, wait); err != nil {
+			return res, err
+		}
+	}
+}
+
+func (bd *batchDownloader) processURLOnce(ctx context.Context, url *nurl.URL, crawlDelay time.Duration) (*fetchedPage, *trafilatura.ExtractResult, error) {
+	// If user only wants to use cache, there is no need to download
+	if bd.cacheOnly {
+		page := bd.httpCache.page(url)
+		if page == nil {
+			return nil, nil, errPageNotCached
+		}
+
+		bd.archivePage(page)
+		result, err := extractPage(page, bd.extractOptions)
+		return page, result, err
+	}
+
+	// Wait for our turn in the host. This is done before acquiring the global
+	// semaphore, to make sure a busy host won't block downloads for other hosts.
+	releaseHost, err := bd.hostLimiter.acquire(ctx, url, crawlDelay)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer releaseHost()
+
+	// Acquire semaphore to limit concurrent download, then make sure the delay
+	// for the host is still respected when the request is actually sent
+	err = bd.hostLimiter.waitSend(ctx, url, crawlDelay, bd.semaphore)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer bd.semaphore.Release(1)
+
+	// Prepare header for conditional request
+	var header http.Header
+	if bd.httpCache != nil {
+		header = bd.httpCache.get(url).requestHeader()
+	}
+
+	// Download URL and report the result to host limiter
+	page, err := fetchPage(bd.httpClient, bd.userAgent, url, header, bd.extractOptions.MaxInputBytes)
+	if retryable, retryAfter := isRetryableError(err); retryable {
+		bd.hostLimiter.reportFailure(url, retryAfter)
+	} else {
+		bd.hostLimiter.reportSuccess(url)
+	}
+
+	// Save the response to WARC file, even when it's unsuccessful or not HTML. However,
+	// the page that too large is skipped since its body is not downloaded completely.
+	if page != nil && !errors.Is(err, errNotModified) && !errors.Is(err, errPageTooLarge) {
+		bd.archivePage(page)
+	}
+
+	if err != nil {
+		return page, nil, err
+	}
+
+	// Extract the page
+	result, err := extractPage(page, bd.extractOptions)
+	return page, result, err
+}
+
+// cachePage saves the page into HTTP cache, if it's enabled. It's only done once the
+// extraction result has been written, otherwise the next run would skip the page as
+// unmodified although its result is missing.
+func (bd *batchDownloader) cachePage(page *fetchedPage) {
+	if bd.httpCache == nil || bd.cacheOnly || page == nil {
+		return
+	}
+
+	if err := bd.httpCache.put(page); err != nil {
+		logrus.Warnf("failed to cache %s: %v", page.URL.String(), err)
+	}
+}
+
+// archivePage saves the page into WARC file, if it's enabled.
+func (bd *batchDownloader) archivePage(page *fetchedPage) {
+	if bd.warcWriter == nil {
+		return
+	}
+
+	if err := bd.warcWriter.writeResponse(page); err != nil {
+		logrus.Warnf("failed to write %s to WARC file: %v", page.URL.String(), err)
+	}
+}
+
+// saveRecord saves the crawl record into state file and JSON Lines stream, if they're enabled.
+func (bd *batchDownloader) saveRecord(record crawlRecord, result *trafilatura.ExtractResult) {
+	record.UpdatedAt = time.Now().UTC()
+
+	if bd.state != nil {
+		if err := bd.state.update(record); err != nil {
+			logrus.Warnf("failed to save state of %s: %v", record.URL, err)
+		}
+	}
+
+	if bd.jsonlWriter != nil {
+		if err := bd.jsonlWriter.write(record, result); err != nil {
+			logrus.Warnf("failed to write %s to JSONL output: %v", record.URL, err)
+		}
+	}
+}
+
+// saveMetrics writes the extraction metrics into file in Prometheus text format.
+func saveMetrics(path string, metrics *trafilatura.PrometheusMetrics) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return metrics.WritePrometheus(f)
 }
@@ -27,12 +27,10 @@ import (
 	fp "path/filepath"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/markusmobius/go-trafilatura"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
-	"golang.org/x/sync/semaphore"
 )
 
 func batchCmd() *cobra.Command {
@@ -49,19 +47,15 @@ func batchCmd() *cobra.Command {
 
 	flags := cmd.Flags()
 	flags.StringP("output", "o", ".", "output directory for the result (default current work dir)")
-	flags.Int("parallel", 10, "number of concurrent download at a time (default 10)")
-	flags.Int("delay", 0, "delay between each url download in seconds (default 0)")
 
+	addDownloaderFlags(cmd)
 	return cmd
 }
 
 func batchCmdHandler(cmd *cobra.Command, args []string) {
 	// Parse arguments
 	flags := cmd.Flags()
-	delay, _ := flags.GetInt("delay")
-	nThread, _ := flags.GetInt("parallel")
 	outputDir, _ := flags.GetString("output")
-	userAgent, _ := cmd.Flags().GetString("user-agent")
 
 	// Parse input file
 	urls, names, err := parseBatchFile(cmd, args[0])
@@ -77,26 +71,20 @@ func batchCmdHandler(cmd *cobra.Command, args []string) {
 	os.MkdirAll(outputDir, os.ModePerm)
 
 	// Download and process concurrently
-	fnWrite := func(result *trafilatura.ExtractResult, url *nurl.URL, idx int) error {
+	fnWrite := func(result *trafilatura.ExtractResult, url *nurl.URL, idx int) (string, error) {
 		name := names[idx]
-		dst, err := os.Create(fp.Join(outputDir, name))
+		dstPath := fp.Join(outputDir, name)
+		dst, err := os.Create(dstPath)
 		if err != nil {
-			return err
+			return "", err
 		}
 		defer dst.Close()
 
-		return writeOutput(dst, result, cmd)
+		return dstPath, writeOutput(dst, result, cmd)
 	}
 
-	err = (&batchDownloader{
-		userAgent:      userAgent,
-		httpClient:     createHttpClient(cmd),
-		extractOptions: createExtractorOptions(cmd),
-		semaphore:      semaphore.NewWeighted(int64(nThread)),
-		delay:          time.Duration(delay) * time.Second,
-		cancelOnError:  false,
-		writeFunc:      fnWrite,
-	}).downloadURLs(context.Background(), urls)
+	err = newBatchDownloader(cmd, createHttpClient(cmd), fnWrite).
+		downloadURLs(context.Background(), urls)
 
 	if err != nil {
 		logrus.Fatalf("process failed: %v", err)
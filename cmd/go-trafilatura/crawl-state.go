@@ -0,0 +1,299 @@
+// This file is part of go-trafilatura, Go package for extracting readable
+// content, comments and metadata from a web page. Source available in
+// <https://github.com/markusmobius/go-trafilatura>.
+// Copyright (C) 2021 Markus Mobius
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or (at your
+// option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+// or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License
+// for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	crawlStatusDone      = "done"
+	crawlStatusFailed    = "failed"
+	crawlStatusSkipped   = "skipped"
+	crawlStatusUnchanged = "unchanged"
+)
+
+// crawlRecord is the state of a single URL in crawl.
+type crawlRecord struct {
+	URL        string    `json:"url"`
+	Status     string    `json:"status"`
+	Attempts   int       `json:"attempts"`
+	HTTPStatus int       `json:"httpStatus,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	OutputFile string    `json:"outputFile,omitempty"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// crawlState records the state of each URL in JSON Lines file, so an interrupted
+// crawl can be resumed later. Every update is appended as a new line, and when
+// the file is loaded the last line for each URL wins.
+type crawlState struct {
+	sync.Mutex
+
+	file    *os.File
+	records map[string]*crawlRecord
+
+	// URLs that updated in the current run, and the number of URLs that skipped
+	// since they have been done in the previous runs.
+	updated  map[string]struct{}
+	nResumed int
+}
+
+// openCrawlState opens the state file, or create it if it doesn't exist yet. If the
+// last line is incomplete because the previous run was killed, it's removed so the
+// new records are not appended to it.
+func openCrawlState(path string) (*crawlState, error) {
+	records, validSize, err := readCrawlRecords(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = repairCrawlState(f, validSize); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	if records == nil {
+		records = make(map[string]*crawlRecord)
+	}
+
+	return &crawlState{
+		file:    f,
+		records: records,
+		updated: make(map[string]struct{}),
+	}, nil
+}
+
+// repairCrawlState truncates the state file to its valid records, and makes
+// sure it ends with a line break.
+func repairCrawlState(f *os.File, validSize int64) error {
+	stat, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	if stat.Size() > validSize {
+		if err := f.Truncate(validSize); err != nil {
+			return err
+		}
+	}
+
+	if validSize == 0 {
+		return nil
+	}
+
+	lastByte := make([]byte, 1)
+	if _, err := f.ReadAt(lastByte, validSize-1); err != nil {
+		return err
+	}
+
+	if lastByte[0] != '\n' {
+		_, err = f.Write([]byte{'\n'})
+	}
+	return err
+}
+
+// loadCrawlRecords reads the state file and returns the latest record for each URL.
+func loadCrawlRecords(path string) (map[string]*crawlRecord, error) {
+	records, _, err := readCrawlRecords(path)
+	return records, err
+}
+
+// readCrawlRecords reads the state file and returns the latest record for each URL,
+// along with the size of the file that contains valid records.
+func readCrawlRecords(path string) (map[string]*crawlRecord, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+
+	var validSize int64
+	records := make(map[string]*crawlRecord)
+	reader := bufio.NewReader(f)
+	for lineNumber := 1; ; lineNumber++ {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			var record crawlRecord
+			if jsonErr := json.Unmarshal(line, &record); jsonErr != nil {
+				// The last line might be incomplete if previous run was killed
+				if err == io.EOF {
+					break
+				}
+				return nil, 0, fmt.Errorf("invalid state in line %d: %w", lineNumber, jsonErr)
+			}
+
+			if record.URL != "" {
+				records[record.URL] = &record
+			}
+			validSize += int64(len(line))
+		}
+
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	return records, validSize, nil
+}
+
+// isDone checks if the URL has been processed successfully.
+func (cs *crawlState) isDone(url string) bool {
+	cs.Lock()
+	defer cs.Unlock()
+
+	record, exist := cs.records[url]
+	return exist && record.Status == crawlStatusDone
+}
+
+// markResumed records that the URL is skipped in the current run, since it has been
+// done in the previous runs.
+func (cs *crawlState) markResumed() {
+	cs.Lock()
+	defer cs.Unlock()
+	cs.nResumed++
+}
+
+// update saves the new state of a URL. The number of attempts is added
+// to the attempts that done in the previous runs.
+func (cs *crawlState) update(record crawlRecord) error {
+	cs.Lock()
+	defer cs.Unlock()
+
+	if previous, exist := cs.records[record.URL]; exist {
+		record.Attempts += previous.Attempts
+	}
+
+	record.UpdatedAt = time.Now().UTC()
+	cs.records[record.URL] = &record
+	cs.updated[record.URL] = struct{}{}
+
+	line, err := json.Marshal(&record)
+	if err != nil {
+		return err
+	}
+
+	_, err = cs.file.Write(append(line, '\n'))
+	return err
+}
+
+// Close closes the state file.
+func (cs *crawlState) Close() error {
+	return cs.file.Close()
+}
+
+// crawlSummary is the summary of crawl state.
+type crawlSummary struct {
+	Total        int            `json:"total"`
+	Resumed      int            `json:"resumed,omitempty"`
+	ByStatus     map[string]int `json:"byStatus"`
+	ByHTTPStatus map[int]int    `json:"byHttpStatus"`
+	Failed       []crawlRecord  `json:"failed"`
+}
+
+func summarizeCrawlRecords(records map[string]*crawlRecord) crawlSummary {
+	summary := crawlSummary{
+		Total:        len(records),
+		ByStatus:     make(map[string]int),
+		ByHTTPStatus: make(map[int]int),
+	}
+
+	for _, record := range records {
+		summary.ByStatus[record.Status]++
+		if record.HTTPStatus != 0 {
+			summary.ByHTTPStatus[record.HTTPStatus]++
+		}
+
+		if record.Status == crawlStatusFailed {
+			summary.Failed = append(summary.Failed, *record)
+		}
+	}
+
+	sort.Slice(summary.Failed, func(i, j int) bool {
+		return summary.Failed[i].URL < summary.Failed[j].URL
+	})
+
+	return summary
+}
+
+// runSummary returns the summary of the current run. The URLs that done in the
+// previous runs are counted separately as resumed.
+func (cs *crawlState) runSummary() crawlSummary {
+	cs.Lock()
+	defer cs.Unlock()
+
+	records := make(map[string]*crawlRecord)
+	for url := range cs.updated {
+		records[url] = cs.records[url]
+	}
+
+	summary := summarizeCrawlRecords(records)
+	summary.Total += cs.nResumed
+	summary.Resumed = cs.nResumed
+	return summary
+}
+
+// writeText writes the summary as human readable text.
+func (s crawlSummary) writeText(w io.Writer) error {
+	buffer := bufio.NewWriter(w)
+	fmt.Fprintf(buffer, "Total URLs: %d\n", s.Total)
+	for _, status := range []string{crawlStatusDone, crawlStatusUnchanged, crawlStatusFailed, crawlStatusSkipped} {
+		fmt.Fprintf(buffer, "%-11s %d\n", status+":", s.ByStatus[status])
+	}
+
+	if s.Resumed > 0 {
+		fmt.Fprintf(buffer, "%-11s %d (done in previous runs)\n", "resumed:", s.Resumed)
+	}
+
+	if len(s.ByHTTPStatus) > 0 {
+		var codes []int
+		for code := range s.ByHTTPStatus {
+			codes = append(codes, code)
+		}
+		sort.Ints(codes)
+
+		fmt.Fprintln(buffer, "\nHTTP status:")
+		for _, code := range codes {
+			fmt.Fprintf(buffer, "  %d: %d\n", code, s.ByHTTPStatus[code])
+		}
+	}
+
+	if len(s.Failed) > 0 {
+		fmt.Fprintln(buffer, "\nFailed URLs:")
+		for _, record := range s.Failed {
+			fmt.Fprintf(buffer, "  %s (%d attempts): %s\n", record.URL, record.Attempts, record.Error)
+		}
+	}
+
+	return buffer.Flush()
+}
@@ -0,0 +1,73 @@
+// This file is part of go-trafilatura, Go package for extracting readable
+// content, comments and metadata from a web page. Source available in
+// <https://github.com/markusmobius/go-trafilatura>.
+// Copyright (C) 2021 Markus Mobius
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or (at your
+// option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+// or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License
+// for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_crawlStateResumeAfterCrash(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.jsonl")
+
+	// First run, which is killed while writing a record
+	state, err := openCrawlState(path)
+	assert.Nil(t, err)
+	assert.Nil(t, state.update(crawlRecord{URL: "https://example.org/a", Status: crawlStatusDone, Attempts: 1}))
+	assert.Nil(t, state.update(crawlRecord{URL: "https://example.org/b", Status: crawlStatusFailed, Attempts: 1}))
+	_, err = state.file.WriteString(`{"url":"https://example.org/c","sta`)
+	assert.Nil(t, err)
+	assert.Nil(t, state.Close())
+
+	// Resume twice, each of them must be able to read the state of previous runs
+	for run, url := range []string{"https://example.org/b", "https://example.org/c"} {
+		state, err = openCrawlState(path)
+		if !assert.Nil(t, err, run) {
+			return
+		}
+
+		assert.True(t, state.isDone("https://example.org/a"), run)
+		assert.False(t, state.isDone(url), run)
+		assert.Nil(t, state.update(crawlRecord{URL: url, Status: crawlStatusDone, Attempts: 1}), run)
+		assert.Nil(t, state.Close(), run)
+	}
+
+	records, err := loadCrawlRecords(path)
+	assert.Nil(t, err)
+	assert.Len(t, records, 3)
+	assert.Equal(t, crawlStatusDone, records["https://example.org/c"].Status)
+	assert.Equal(t, 2, records["https://example.org/b"].Attempts)
+
+	// Valid record without trailing line break is kept
+	content, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Nil(t, os.WriteFile(path, content[:len(content)-1], 0644))
+
+	state, err = openCrawlState(path)
+	assert.Nil(t, err)
+	assert.Nil(t, state.update(crawlRecord{URL: "https://example.org/d", Status: crawlStatusDone}))
+	assert.Nil(t, state.Close())
+
+	records, err = loadCrawlRecords(path)
+	assert.Nil(t, err)
+	assert.Len(t, records, 4)
+}
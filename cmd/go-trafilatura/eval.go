@@ -0,0 +1,128 @@
+// This file is part of go-trafilatura, Go package for extracting readable
+// content, comments and metadata from a web page. Source available in
+// <https://github.com/markusmobius/go-trafilatura>.
+// Copyright (C) 2021 Markus Mobius
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or (at your
+// option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+// or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License
+// for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"os"
+
+	"github.com/markusmobius/go-trafilatura/eval"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func evalCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "eval [flags] [gold-set]",
+		Short: "Evaluate extractors against a gold set",
+		Long: "Evaluate extractors against a gold set, which is a JSON or YAML file that contains\n" +
+			"list of documents (url and file) along with the snippets that must and must not\n" +
+			"be found in the extracted content (mustContain and mustNotContain). It reports the\n" +
+			"precision, recall, F1, accuracy and throughput of each extractor. Comments are\n" +
+			"always excluded since the gold set only evaluates the main content.",
+		Args: cobra.ExactArgs(1),
+		Run:  evalCmdHandler,
+	}
+
+	flags := cmd.Flags()
+	flags.StringSlice("modes", []string{"trafilatura", "fallback", "readability", "dom-distiller"},
+		"extractors to evaluate: nothing, everything, trafilatura, fallback, readability and dom-distiller")
+	flags.String("report", "", "path to file for saving the report in JSON format")
+	flags.String("compare", "", "path to the previous JSON report, used as baseline for finding regressions")
+	flags.Float64("tolerance", 0.005, "max drop of score before it's considered as regression")
+	flags.Bool("diff", false, "print missing and unwanted snippets for each document")
+	return cmd
+}
+
+func evalCmdHandler(cmd *cobra.Command, args []string) {
+	// Parse flags
+	flags := cmd.Flags()
+	strModes, _ := flags.GetStringSlice("modes")
+	reportPath, _ := flags.GetString("report")
+	baselinePath, _ := flags.GetString("compare")
+	tolerance, _ := flags.GetFloat64("tolerance")
+	withDiffs, _ := flags.GetBool("diff")
+
+	var modes []eval.Mode
+	for _, mode := range strModes {
+		modes = append(modes, eval.Mode(mode))
+	}
+
+	opts := createExtractorOptions(cmd)
+	opts.ExcludeComments = true
+
+	// Load the gold set and evaluate it
+	goldSet, err := eval.LoadGoldSet(args[0])
+	if err != nil {
+		logrus.Fatalf("failed to load gold set: %v", err)
+	}
+
+	report, err := eval.Evaluate(goldSet, eval.Config{
+		Modes:      modes,
+		Options:    opts,
+		HttpClient: createHttpClient(cmd),
+	})
+	if err != nil {
+		logrus.Fatalf("evaluation failed: %v", err)
+	}
+
+	// Print and save the report
+	outputFormat, _ := flags.GetString("format")
+	if outputFormat == "json" {
+		err = report.WriteJSON(os.Stdout)
+	} else {
+		err = report.WriteText(os.Stdout, withDiffs)
+	}
+
+	if err != nil {
+		logrus.Fatalf("failed to write report: %v", err)
+	}
+
+	if reportPath != "" {
+		if err := saveEvalReport(reportPath, report); err != nil {
+			logrus.Fatalf("failed to save report: %v", err)
+		}
+	}
+
+	// Compare with baseline, exit with error if there are regressions
+	if baselinePath != "" {
+		baseline, err := eval.LoadReport(baselinePath)
+		if err != nil {
+			logrus.Fatalf("failed to load baseline report: %v", err)
+		}
+
+		regressions := report.Compare(baseline, tolerance)
+		for _, regression := range regressions {
+			logrus.Warnln("regression:", regression.String())
+		}
+
+		if len(regressions) > 0 {
+			logrus.Fatalf("found %d regressions", len(regressions))
+		}
+	}
+}
+
+func saveEvalReport(path string, report *eval.Report) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return report.WriteJSON(f)
+}
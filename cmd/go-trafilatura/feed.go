@@ -38,7 +38,6 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"golang.org/x/net/html"
-	"golang.org/x/sync/semaphore"
 )
 
 const (
@@ -62,14 +61,13 @@ func feedCmd() *cobra.Command {
 
 	flags := cmd.Flags()
 	flags.StringP("output", "o", ".", "output directory for the result (default current work dir)")
-	flags.Int("parallel", 10, "number of concurrent download at a time (default 10)")
-	flags.Int("delay", 0, "delay between each url download in seconds (default 0)")
 	flags.String("filter", "", "regular expression for allowed url")
 	flags.String("exclude", "", "regular expression for excluded url")
 	flags.StringArray("domains", nil, "list of allowed domains")
 	flags.StringArray("no-domains", nil, "list of excluded domains")
 	flags.Bool("url-only", false, "only print page urls without downloading or processing them")
 
+	addDownloaderFlags(cmd)
 	return cmd
 }
 
@@ -84,8 +82,6 @@ type feedCmdHandler struct {
 func newFeedCmdHandler(cmd *cobra.Command) *feedCmdHandler {
 	// Parse flags
 	flags := cmd.Flags()
-	delay, _ := flags.GetInt("delay")
-	nThread, _ := flags.GetInt("parallel")
 	allowedPattern, _ := flags.GetString("filter")
 	excludedPattern, _ := flags.GetString("exclude")
 	allowedDomains, _ := flags.GetStringArray("domains")
@@ -130,33 +126,26 @@ func newFeedCmdHandler(cmd *cobra.Command) *feedCmdHandler {
 
 	// Prepare pages downloader
 	nameExt := outputExt(cmd)
-	fnWrite := func(result *trafilatura.ExtractResult, url *nurl.URL, idx int) error {
+	fnWrite := func(result *trafilatura.ExtractResult, url *nurl.URL, idx int) (string, error) {
 		name := nameFromURL(url)
 		timestamp := time.Now().Format("150405")
 		id, err := gonanoid.New(6)
 		if err != nil {
-			return err
+			return "", err
 		}
 
 		name = timestamp + "-" + name + "-" + id + nameExt
-		dst, err := os.Create(fp.Join(outputDir, name))
+		dstPath := fp.Join(outputDir, name)
+		dst, err := os.Create(dstPath)
 		if err != nil {
-			return err
+			return "", err
 		}
 		defer dst.Close()
 
-		return writeOutput(dst, result, cmd)
+		return dstPath, writeOutput(dst, result, cmd)
 	}
 
-	pagesDownloader := &batchDownloader{
-		userAgent:      userAgent,
-		httpClient:     httpClient,
-		extractOptions: createExtractorOptions(cmd),
-		semaphore:      semaphore.NewWeighted(int64(nThread)),
-		delay:          time.Duration(delay) * time.Second,
-		cancelOnError:  false,
-		writeFunc:      fnWrite,
-	}
+	pagesDownloader := newBatchDownloader(cmd, httpClient, fnWrite)
 
 	// Make sure output dir exist
 	os.MkdirAll(outputDir, os.ModePerm)
@@ -190,6 +179,7 @@ func (fch *feedCmdHandler) run(args []string) {
 		for _, url := range pageURLs {
 			fmt.Println(url.String())
 		}
+		fch.pagesDownloader.close()
 		return
 	}
 
@@ -0,0 +1,181 @@
+// This file is part of go-trafilatura, Go package for extracting readable
+// content, comments and metadata from a web page. Source available in
+// <https://github.com/markusmobius/go-trafilatura>.
+// Copyright (C) 2021 Markus Mobius
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or (at your
+// option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+// or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License
+// for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path"
+	fp "path/filepath"
+	"strings"
+	"sync/atomic"
+
+	"github.com/markusmobius/go-trafilatura"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/semaphore"
+)
+
+func filesCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "files [flags] [source...]",
+		Short: "Extract pages from local files, directories or archives",
+		Long: "Extract pages from local files. The source can be a directory which will be read\n" +
+			"recursively, a glob pattern, a tar, tar.gz or zip archive, or \"-\" to read from\n" +
+			"stdin. The extract result will be saved in output directory that mirrors the tree\n" +
+			"of the source, using extension of the output format. Only \".html\" extension of the\n" +
+			"source is replaced, so \"a.htm\" is saved as \"a.htm.md\" instead of overwriting \"a.md\".",
+		Args: cobra.MinimumNArgs(1),
+		Run:  filesCmdHandler,
+	}
+
+	flags := cmd.Flags()
+	flags.StringP("output", "o", ".", "output directory for the result (default current work dir)")
+	flags.Int("parallel", 10, "number of concurrent extraction at a time (default 10)")
+	return cmd
+}
+
+func filesCmdHandler(cmd *cobra.Command, args []string) {
+	// Parse arguments
+	flags := cmd.Flags()
+	outputDir, _ := flags.GetString("output")
+	nThread, _ := flags.GetInt("parallel")
+
+	fe := &fileExtractor{
+		cmd:            cmd,
+		extractOptions: createExtractorOptions(cmd),
+		semaphore:      semaphore.NewWeighted(int64(nThread)),
+		nameExt:        outputExt(cmd),
+	}
+
+	// Process each source. If there are several sources, each of them
+	// is put in its own sub directory to prevent name collision.
+	for _, source := range args {
+		dstDir := outputDir
+		if len(args) > 1 {
+			dstDir = fp.Join(outputDir, sourceDirName(source))
+		}
+
+		if err := fe.processSource(context.Background(), source, dstDir); err != nil {
+			logrus.Fatalf("failed to process %s: %v", source, err)
+		}
+	}
+
+	logrus.Printf("extracted %d of %d files", fe.nExtracted, fe.nFiles)
+}
+
+type fileExtractor struct {
+	cmd            *cobra.Command
+	extractOptions trafilatura.Options
+	semaphore      *semaphore.Weighted
+	nameExt        string
+	nFiles         int64
+	nExtracted     int64
+}
+
+func (fe *fileExtractor) processSource(ctx context.Context, source string, dstDir string) error {
+	g, ctx := errgroup.WithContext(ctx)
+
+	err := walkSource(source, func(file localFile) error {
+		if !isHTMLFile(file) {
+			return nil
+		}
+
+		// Acquire semaphore before starting goroutine, so we don't
+		// read too many files into memory at the same time
+		if err := fe.semaphore.Acquire(ctx, 1); err != nil {
+			return err
+		}
+
+		g.Go(func() error {
+			defer fe.semaphore.Release(1)
+			fe.processFile(file, dstDir)
+			return nil
+		})
+
+		return nil
+	})
+
+	if gErr := g.Wait(); err == nil {
+		err = gErr
+	}
+
+	return err
+}
+
+func (fe *fileExtractor) processFile(file localFile, dstDir string) {
+	atomic.AddInt64(&fe.nFiles, 1)
+
+	// Extract the file
+	result, err := trafilatura.Extract(bytes.NewReader(file.Content), fe.extractOptions)
+	if err != nil {
+		logrus.Warnf("failed to extract %s: %v", file.Path, err)
+		return
+	}
+
+	// Mirror the file path in output dir
+	dstPath := fp.Join(dstDir, fp.FromSlash(outputFileName(file.Path, fe.nameExt)))
+	if err := os.MkdirAll(fp.Dir(dstPath), os.ModePerm); err != nil {
+		logrus.Warnf("failed to create dir for %s: %v", file.Path, err)
+		return
+	}
+
+	dst, err := os.Create(dstPath)
+	if err != nil {
+		logrus.Warnf("failed to create output for %s: %v", file.Path, err)
+		return
+	}
+	defer dst.Close()
+
+	if err := writeOutput(dst, result, fe.cmd); err != nil {
+		logrus.Warnf("failed to write %s: %v", file.Path, err)
+		return
+	}
+
+	atomic.AddInt64(&fe.nExtracted, 1)
+}
+
+// outputFileName returns the path of output file for the source file. Only ".html" extension
+// is replaced, the other extensions are kept so "a.html" and "a.htm" don't overwrite each other.
+func outputFileName(filePath string, nameExt string) string {
+	if ext := path.Ext(filePath); strings.EqualFold(ext, ".html") {
+		filePath = strings.TrimSuffix(filePath, ext)
+	}
+	return filePath + nameExt
+}
+
+// sourceDirName returns name of sub directory for the source in output dir.
+func sourceDirName(source string) string {
+	if source == "-" {
+		return "stdin"
+	}
+
+	name := fp.Base(source)
+	if strings.ContainsAny(source, "*?[") {
+		name = fp.Base(globBaseDir(source))
+	}
+
+	for _, ext := range []string{".gz", ".tgz", ".tar", ".zip"} {
+		name = strings.TrimSuffix(name, ext)
+	}
+
+	return name
+}
@@ -0,0 +1,290 @@
+// This file is part of go-trafilatura, Go package for extracting readable
+// content, comments and metadata from a web page. Source available in
+// <https://github.com/markusmobius/go-trafilatura>.
+// Copyright (C) 2021 Markus Mobius
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or (at your
+// option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+// or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License
+// for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	nurl "net/url"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sync/semaphore"
+)
+
+// hostLimiter limits downloads for every host. For each host it limits the number
+// of concurrent downloads, limits the request rate using token bucket, and works as
+// circuit breaker that pauses the host after several consecutive failures.
+type hostLimiter struct {
+	sync.Mutex
+
+	maxConcurrent int64
+	rate          float64
+	burst         int
+	delay         time.Duration
+	maxFailures   int
+	cooldown      time.Duration
+	hosts         map[string]*hostState
+}
+
+type hostState struct {
+	sync.Mutex
+
+	semaphore   *semaphore.Weighted
+	tokens      float64
+	lastRefill  time.Time
+	lastTaken   time.Time
+	lastSent    time.Time
+	nFailures   int
+	pausedUntil time.Time
+}
+
+// hostLimiterConfig is the configuration for host limiter.
+type hostLimiterConfig struct {
+	// MaxConcurrent is the max number of concurrent downloads for each host.
+	// If it's zero or negative, there are no limit.
+	MaxConcurrent int
+
+	// Rate is the max number of requests per second for each host.
+	// If it's zero or negative, there are no limit.
+	Rate float64
+
+	// Burst is the max number of requests that allowed to exceed the rate.
+	Burst int
+
+	// Delay is the minimum delay between requests to the same host. Unlike
+	// the rate, the burst is not applied to it.
+	Delay time.Duration
+
+	// MaxFailures is the number of consecutive failures before the host is
+	// paused. If it's zero or negative, the host will never be paused.
+	MaxFailures int
+
+	// Cooldown is how long the host paused after too many failures.
+	Cooldown time.Duration
+}
+
+func newHostLimiter(cfg hostLimiterConfig) *hostLimiter {
+	if cfg.Burst <= 0 {
+		cfg.Burst = 1
+	}
+
+	return &hostLimiter{
+		maxConcurrent: int64(cfg.MaxConcurrent),
+		rate:          cfg.Rate,
+		burst:         cfg.Burst,
+		delay:         cfg.Delay,
+		maxFailures:   cfg.MaxFailures,
+		cooldown:      cfg.Cooldown,
+		hosts:         make(map[string]*hostState),
+	}
+}
+
+func (hl *hostLimiter) state(url *nurl.URL) *hostState {
+	hl.Lock()
+	defer hl.Unlock()
+
+	host := url.Hostname()
+	state, exist := hl.hosts[host]
+	if !exist {
+		state = &hostState{tokens: float64(hl.burst)}
+		if hl.maxConcurrent > 0 {
+			state.semaphore = semaphore.NewWeighted(hl.maxConcurrent)
+		}
+		hl.hosts[host] = state
+	}
+
+	return state
+}
+
+// rateInterval returns the interval for refilling a token in host's bucket.
+func (hl *hostLimiter) rateInterval() time.Duration {
+	if hl.rate <= 0 {
+		return 0
+	}
+	return time.Duration(float64(time.Second) / hl.rate)
+}
+
+// minDelay returns the minimum delay between requests to a host, which is the
+// largest between the limiter's delay and the specified crawl delay.
+func (hl *hostLimiter) minDelay(crawlDelay time.Duration) time.Duration {
+	if crawlDelay > hl.delay {
+		return crawlDelay
+	}
+	return hl.delay
+}
+
+// acquire blocks until the URL is allowed to be downloaded, i.e. there is a free slot for
+// its host, the host is not paused and there is a token available in the host's bucket.
+// Make sure to call the returned function to release the slot once download finished.
+func (hl *hostLimiter) acquire(ctx context.Context, url *nurl.URL, crawlDelay time.Duration) (func(), error) {
+	state := hl.state(url)
+	rateInterval := hl.rateInterval()
+	minDelay := hl.minDelay(crawlDelay)
+
+	// Wait for free slot
+	if state.semaphore != nil {
+		if err := state.semaphore.Acquire(ctx, 1); err != nil {
+			return nil, err
+		}
+	}
+
+	release := func() {
+		if state.semaphore != nil {
+			state.semaphore.Release(1)
+		}
+	}
+
+	// Wait until host is not paused and token is available
+	for {
+		wait := state.take(rateInterval, minDelay, hl.burst)
+		if wait <= 0 {
+			return release, nil
+		}
+
+		if err := sleepContext(ctx, wait); err != nil {
+			release()
+			return nil, err
+		}
+	}
+}
+
+// waitSend blocks until the request to the URL can be sent, i.e. the global semaphore is
+// acquired and the minimum delay since the last request sent to its host has passed. This
+// is checked again after acquiring the semaphore, since the request might wait there long
+// enough for the other requests to the host to catch up. The semaphore is released while
+// waiting for the delay, so it doesn't block the other hosts.
+func (hl *hostLimiter) waitSend(ctx context.Context, url *nurl.URL, crawlDelay time.Duration, global *semaphore.Weighted) error {
+	state := hl.state(url)
+	minDelay := hl.minDelay(crawlDelay)
+
+	for {
+		if err := global.Acquire(ctx, 1); err != nil {
+			return err
+		}
+
+		wait := state.send(time.Now(), minDelay)
+		if wait <= 0 {
+			return nil
+		}
+
+		global.Release(1)
+		if err := sleepContext(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// reportSuccess tells the limiter that download from the URL's host is succeed,
+// which resets the failure counter of the host.
+func (hl *hostLimiter) reportSuccess(url *nurl.URL) {
+	state := hl.state(url)
+	state.Lock()
+	state.nFailures = 0
+	state.Unlock()
+}
+
+// reportFailure tells the limiter that download from the URL's host is failed. If server
+// asked us to retry later, the host will be paused until then. If there are too many
+// consecutive failures, the host will be paused for cooldown duration.
+func (hl *hostLimiter) reportFailure(url *nurl.URL, retryAfter time.Duration) {
+	state := hl.state(url)
+	state.Lock()
+	defer state.Unlock()
+
+	now := time.Now()
+	state.nFailures++
+	state.pause(now.Add(retryAfter))
+
+	if hl.maxFailures > 0 && state.nFailures >= hl.maxFailures {
+		logrus.Warnf("pausing %s for %v after %d consecutive failures", url.Hostname(), hl.cooldown, state.nFailures)
+		state.pause(now.Add(hl.cooldown))
+
+		// Once the cooldown finished, a single failure is enough to pause the host again
+		state.nFailures = hl.maxFailures - 1
+	}
+}
+
+// take tries to take a token from the bucket, which refilled once every rate interval.
+// The minimum delay since the last taken token is always respected, regardless of the
+// burst. If it's succeed, it returns zero. Otherwise it returns how long we need to
+// wait before trying again.
+func (hs *hostState) take(rateInterval, minDelay time.Duration, burst int) time.Duration {
+	hs.Lock()
+	defer hs.Unlock()
+	return hs.takeAt(time.Now(), rateInterval, minDelay, burst)
+}
+
+func (hs *hostState) takeAt(now time.Time, rateInterval, minDelay time.Duration, burst int) time.Duration {
+	// Check if host is paused
+	if now.Before(hs.pausedUntil) {
+		return hs.pausedUntil.Sub(now)
+	}
+
+	// Check the delay since the last request
+	if minDelay > 0 && !hs.lastTaken.IsZero() {
+		if wait := hs.lastTaken.Add(minDelay).Sub(now); wait > 0 {
+			return wait
+		}
+	}
+
+	// If there are no interval, there is no rate limit
+	if rateInterval > 0 {
+		// Refill the bucket
+		if !hs.lastRefill.IsZero() {
+			elapsed := now.Sub(hs.lastRefill)
+			hs.tokens += float64(elapsed) / float64(rateInterval)
+			if hs.tokens > float64(burst) {
+				hs.tokens = float64(burst)
+			}
+		}
+		hs.lastRefill = now
+
+		// Take the token
+		if hs.tokens < 1 {
+			return time.Duration((1 - hs.tokens) * float64(rateInterval))
+		}
+		hs.tokens--
+	}
+
+	hs.lastTaken = now
+	return 0
+}
+
+// send marks the request as sent if the minimum delay since the last sent request has
+// passed. Otherwise it returns how long we need to wait before trying again.
+func (hs *hostState) send(now time.Time, minDelay time.Duration) time.Duration {
+	hs.Lock()
+	defer hs.Unlock()
+
+	if minDelay > 0 && !hs.lastSent.IsZero() {
+		if wait := hs.lastSent.Add(minDelay).Sub(now); wait > 0 {
+			return wait
+		}
+	}
+
+	hs.lastSent = now
+	return 0
+}
+
+func (hs *hostState) pause(until time.Time) {
+	if until.After(hs.pausedUntil) {
+		hs.pausedUntil = until
+	}
+}
@@ -0,0 +1,126 @@
+// This file is part of go-trafilatura, Go package for extracting readable
+// content, comments and metadata from a web page. Source available in
+// <https://github.com/markusmobius/go-trafilatura>.
+// Copyright (C) 2021 Markus Mobius
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or (at your
+// option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+// or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License
+// for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	nurl "net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sync/semaphore"
+)
+
+func Test_hostStateTake(t *testing.T) {
+	type step struct {
+		at   time.Duration // time since start
+		wait time.Duration // expected wait, zero if token is taken
+	}
+
+	tests := []struct {
+		name         string
+		rateInterval time.Duration
+		minDelay     time.Duration
+		burst        int
+		pausedFor    time.Duration
+		steps        []step
+	}{{
+		name:  "no limit",
+		burst: 1,
+		steps: []step{{0, 0}, {0, 0}, {0, 0}},
+	}, {
+		name:         "rate without burst",
+		rateInterval: time.Second,
+		burst:        1,
+		steps:        []step{{0, 0}, {0, time.Second}, {500 * time.Millisecond, 500 * time.Millisecond}, {time.Second, 0}},
+	}, {
+		name:         "rate with burst",
+		rateInterval: time.Second,
+		burst:        3,
+		steps:        []step{{0, 0}, {0, 0}, {0, 0}, {0, time.Second}, {2 * time.Second, 0}, {2 * time.Second, 0}, {2 * time.Second, time.Second}},
+	}, {
+		name:         "burst doesn't bypass delay",
+		rateInterval: time.Second,
+		minDelay:     2 * time.Second,
+		burst:        3,
+		steps:        []step{{0, 0}, {0, 2 * time.Second}, {time.Second, time.Second}, {2 * time.Second, 0}, {4 * time.Second, 0}},
+	}, {
+		name:     "delay only",
+		minDelay: time.Second,
+		burst:    1,
+		steps:    []step{{0, 0}, {100 * time.Millisecond, 900 * time.Millisecond}, {time.Second, 0}},
+	}, {
+		name:         "paused host",
+		rateInterval: time.Second,
+		burst:        1,
+		pausedFor:    5 * time.Second,
+		steps:        []step{{0, 5 * time.Second}, {4 * time.Second, time.Second}, {5 * time.Second, 0}},
+	}}
+
+	start := time.Now()
+	for _, test := range tests {
+		state := &hostState{tokens: float64(test.burst), pausedUntil: start.Add(test.pausedFor)}
+		for i, step := range test.steps {
+			wait := state.takeAt(start.Add(step.at), test.rateInterval, test.minDelay, test.burst)
+			assert.Equal(t, step.wait, wait, "%s, step %d", test.name, i)
+		}
+	}
+}
+
+func Test_hostLimiterDelayWhileBusy(t *testing.T) {
+	delay := 100 * time.Millisecond
+	hl := newHostLimiter(hostLimiterConfig{MaxConcurrent: 2, Delay: delay})
+	global := semaphore.NewWeighted(1)
+	url, _ := nurl.Parse("https://example.org/page")
+
+	// Keep the global semaphore busy, so both requests get their host token
+	// while waiting for it
+	ctx := context.Background()
+	assert.Nil(t, global.Acquire(ctx, 1))
+
+	var mutex sync.Mutex
+	var sentTimes []time.Time
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			releaseHost, err := hl.acquire(ctx, url, 0)
+			assert.Nil(t, err)
+			defer releaseHost()
+
+			assert.Nil(t, hl.waitSend(ctx, url, 0, global))
+			defer global.Release(1)
+
+			mutex.Lock()
+			sentTimes = append(sentTimes, time.Now())
+			mutex.Unlock()
+		}()
+	}
+
+	time.Sleep(3 * delay)
+	global.Release(1)
+	wg.Wait()
+
+	assert.Len(t, sentTimes, 2)
+	gap := sentTimes[1].Sub(sentTimes[0])
+	assert.GreaterOrEqual(t, int64(gap), int64(delay))
+}
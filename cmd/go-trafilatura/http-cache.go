@@ -0,0 +1,216 @@
+// This file is part of go-trafilatura, Go package for extracting readable
+// content, comments and metadata from a web page. Source available in
+// <https://github.com/markusmobius/go-trafilatura>.
+// Copyright (C) 2021 Markus Mobius
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or (at your
+// option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+// or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License
+// for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	nurl "net/url"
+	"os"
+	fp "path/filepath"
+	"time"
+)
+
+// errPageNotCached is returned when page is required to be loaded from cache, but it's not cached.
+var errPageNotCached = errors.New("page is not cached")
+
+// httpCache is an on-disk cache for downloaded pages. For each URL it saves the
+// validators (ETag and Last-Modified) so the next download can be done using
+// conditional request. Optionally it also saves the raw HTML, so the page can be
+// extracted again later without downloading it.
+type httpCache struct {
+	dir       string
+	storeBody bool
+}
+
+// httpCacheEntry is the cached data for a single URL.
+type httpCacheEntry struct {
+	URL          string    `json:"url"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"lastModified,omitempty"`
+	ContentType  string    `json:"contentType,omitempty"`
+	FetchedAt    time.Time `json:"fetchedAt"`
+	HasBody      bool      `json:"hasBody,omitempty"`
+}
+
+func newHttpCache(dir string, storeBody bool) (*httpCache, error) {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	return &httpCache{
+		dir:       dir,
+		storeBody: storeBody,
+	}, nil
+}
+
+// paths returns path to the metadata and body file of the URL.
+func (hc *httpCache) paths(url *nurl.URL) (string, string) {
+	hash := sha1.Sum([]byte(url.String()))
+	name := hex.EncodeToString(hash[:])
+	basePath := fp.Join(hc.dir, name[:2], name)
+	return basePath + ".json", basePath + ".html"
+}
+
+// get returns the cache entry for the URL. If the URL is not cached, it returns nil.
+func (hc *httpCache) get(url *nurl.URL) *httpCacheEntry {
+	metaPath, _ := hc.paths(url)
+	f, err := os.Open(metaPath)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var entry httpCacheEntry
+	if err := json.NewDecoder(f).Decode(&entry); err != nil {
+		return nil
+	}
+
+	return &entry
+}
+
+// put saves the downloaded page into cache.
+func (hc *httpCache) put(page *fetchedPage) error {
+	entry := httpCacheEntry{
+		URL:          page.URL.String(),
+		ETag:         page.Header.Get("ETag"),
+		LastModified: page.Header.Get("Last-Modified"),
+		ContentType:  page.Header.Get("Content-Type"),
+		FetchedAt:    time.Now().UTC(),
+		HasBody:      hc.storeBody,
+	}
+
+	// If there are no validators and body is not stored, the cache is useless. The old
+	// entry is removed, so the next request doesn't use the outdated validators.
+	metaPath, bodyPath := hc.paths(page.URL)
+	if entry.ETag == "" && entry.LastModified == "" && !hc.storeBody {
+		return removeFiles(metaPath, bodyPath)
+	}
+
+	if err := os.MkdirAll(fp.Dir(metaPath), os.ModePerm); err != nil {
+		return err
+	}
+
+	// Body is saved before metadata, so the metadata never refers to missing body
+	if hc.storeBody {
+		if err := writeFileAtomic(bodyPath, page.Body); err != nil {
+			return err
+		}
+	}
+
+	metadata, err := json.Marshal(&entry)
+	if err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(metaPath, metadata); err != nil {
+		return err
+	}
+
+	// The body from the previous download is outdated and no longer referred
+	if !hc.storeBody {
+		return removeFiles(bodyPath)
+	}
+
+	return nil
+}
+
+// removeFiles removes the files in order, ignoring the ones that don't exist.
+func removeFiles(paths ...string) error {
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeFileAtomic writes data into temporary file then renames it to the path, so
+// the interrupted write won't leave a partial file in cache.
+func writeFileAtomic(path string, data []byte) error {
+	f, err := os.CreateTemp(fp.Dir(path), fp.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+
+	tmpPath := f.Name()
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Chmod(tmpPath, 0644)
+	}
+
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	return nil
+}
+
+// page returns the cached page for the URL. It returns nil if body is not cached.
+func (hc *httpCache) page(url *nurl.URL) *fetchedPage {
+	entry := hc.get(url)
+	if entry == nil || !entry.HasBody {
+		return nil
+	}
+
+	_, bodyPath := hc.paths(url)
+	body, err := os.ReadFile(bodyPath)
+	if err != nil {
+		return nil
+	}
+
+	header := http.Header{}
+	header.Set("Content-Type", entry.ContentType)
+	return &fetchedPage{
+		URL:        url,
+		StatusCode: http.StatusOK,
+		Header:     header,
+		Body:       body,
+	}
+}
+
+// requestHeader returns header for conditional request.
+func (e *httpCacheEntry) requestHeader() http.Header {
+	if e == nil {
+		return nil
+	}
+
+	header := http.Header{}
+	if e.ETag != "" {
+		header.Set("If-None-Match", e.ETag)
+	}
+
+	if e.LastModified != "" {
+		header.Set("If-Modified-Since", e.LastModified)
+	}
+
+	return header
+}
@@ -0,0 +1,64 @@
+// This file is part of go-trafilatura, Go package for extracting readable
+// content, comments and metadata from a web page. Source available in
+// <https://github.com/markusmobius/go-trafilatura>.
+// Copyright (C) 2021 Markus Mobius
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or (at your
+// option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+// or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License
+// for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"net/http"
+	nurl "net/url"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_httpCachePut(t *testing.T) {
+	dir := t.TempDir()
+	url, _ := nurl.ParseRequestURI("https://example.org/page")
+	newPage := func(etag string, body string) *fetchedPage {
+		header := http.Header{}
+		header.Set("Content-Type", "text/html")
+		if etag != "" {
+			header.Set("ETag", etag)
+		}
+		return &fetchedPage{URL: url, StatusCode: http.StatusOK, Header: header, Body: []byte(body)}
+	}
+
+	withBody, err := newHttpCache(dir, true)
+	assert.Nil(t, err)
+	withoutBody, err := newHttpCache(dir, false)
+	assert.Nil(t, err)
+	_, bodyPath := withBody.paths(url)
+
+	// Page with body
+	assert.Nil(t, withBody.put(newPage(`"v1"`, "<p>first</p>")))
+	assert.Equal(t, `"v1"`, withBody.get(url).ETag)
+	assert.Equal(t, "<p>first</p>", string(withBody.page(url).Body))
+
+	// Without storing body, the old body is removed
+	assert.Nil(t, withoutBody.put(newPage(`"v2"`, "<p>second</p>")))
+	assert.Equal(t, `"v2"`, withoutBody.get(url).ETag)
+	assert.Nil(t, withoutBody.page(url))
+	_, err = os.Stat(bodyPath)
+	assert.True(t, os.IsNotExist(err))
+
+	// Without validators, the outdated entry is removed
+	assert.Nil(t, withoutBody.put(newPage("", "<p>third</p>")))
+	assert.Nil(t, withoutBody.get(url))
+	assert.Nil(t, withoutBody.get(url).requestHeader())
+}
@@ -0,0 +1,111 @@
+// This file is part of go-trafilatura, Go package for extracting readable
+// content, comments and metadata from a web page. Source available in
+// <https://github.com/markusmobius/go-trafilatura>.
+// Copyright (C) 2021 Markus Mobius
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or (at your
+// option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+// or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License
+// for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/markusmobius/go-trafilatura"
+)
+
+// jsonlWriter writes crawl result as a stream of JSON Lines, one record per page.
+// The stream is written into stdout or a file, which will be compressed using gzip
+// if its name ends with ".gz".
+type jsonlWriter struct {
+	sync.Mutex
+
+	file     io.Closer
+	gzWriter *gzip.Writer
+	encoder  *json.Encoder
+}
+
+func newJsonlWriter(path string) (*jsonlWriter, error) {
+	var w io.Writer = os.Stdout
+	var file io.Closer
+
+	// Append to the existing file, so records from the previous run of a resumed
+	// crawl are not lost. Gzip allows concatenated streams, so it's fine too.
+	if path != "-" {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, err
+		}
+		w, file = f, f
+	}
+
+	var gzWriter *gzip.Writer
+	if strings.HasSuffix(strings.ToLower(path), ".gz") {
+		gzWriter = gzip.NewWriter(w)
+		w = gzWriter
+	}
+
+	return &jsonlWriter{
+		file:     file,
+		gzWriter: gzWriter,
+		encoder:  json.NewEncoder(w),
+	}, nil
+}
+
+// write writes the crawl record of a page, along with its extract result if it exists.
+func (jw *jsonlWriter) write(record crawlRecord, result *trafilatura.ExtractResult) error {
+	line := map[string]interface{}{}
+	if result != nil {
+		line = jsonExtractResult(*result).toMap()
+	}
+
+	line["url"] = record.URL
+	line["status"] = record.Status
+	line["attempts"] = record.Attempts
+	line["fetchedAt"] = record.UpdatedAt
+
+	if record.HTTPStatus != 0 {
+		line["httpStatus"] = record.HTTPStatus
+	}
+
+	if record.Error != "" {
+		line["error"] = record.Error
+	}
+
+	jw.Lock()
+	defer jw.Unlock()
+	return jw.encoder.Encode(line)
+}
+
+// Close flushes the stream and closes the file.
+func (jw *jsonlWriter) Close() error {
+	jw.Lock()
+	defer jw.Unlock()
+
+	if jw.gzWriter != nil {
+		if err := jw.gzWriter.Close(); err != nil {
+			return err
+		}
+	}
+
+	if jw.file != nil {
+		return jw.file.Close()
+	}
+
+	return nil
+}
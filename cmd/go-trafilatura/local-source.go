@@ -0,0 +1,281 @@
+// This file is part of go-trafilatura, Go package for extracting readable
+// content, comments and metadata from a web page. Source available in
+// <https://github.com/markusmobius/go-trafilatura>.
+// Copyright (C) 2021 Markus Mobius
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or (at your
+// option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+// or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License
+// for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	fp "path/filepath"
+	"strings"
+)
+
+// localFile is a file that read from local source, e.g. directory or archive.
+type localFile struct {
+	// Path is the path of file relative to its source, using slash as separator.
+	Path    string
+	Content []byte
+}
+
+var htmlExtensions = sliceToMap(".html", ".htm", ".xhtml", ".shtml")
+
+// walkSource reads files from the source, which can be a directory (read recursively),
+// an archive (tar, tar.gz or zip), a single file, a glob pattern or "-" for stdin. Only
+// files that might be HTML are passed to the callback.
+func walkSource(source string, fn func(localFile) error) error {
+	if source == "-" {
+		return walkReader(os.Stdin, "stdin", fn)
+	}
+
+	stat, err := os.Stat(source)
+	switch {
+	case err == nil && stat.IsDir():
+		return walkDir(source, fn)
+	case err == nil && strings.EqualFold(fp.Ext(source), ".zip"):
+		return walkZipFile(source, fn)
+	case err == nil:
+		f, err := os.Open(source)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return walkReader(f, fp.Base(source), fn)
+	}
+
+	// Source doesn't exist, so treat it as glob pattern
+	matches, err := fp.Glob(source)
+	if err != nil {
+		return err
+	}
+
+	if len(matches) == 0 {
+		return fmt.Errorf("no file matches %s", source)
+	}
+
+	baseDir := globBaseDir(source)
+	for _, match := range matches {
+		if stat, err := os.Stat(match); err != nil || stat.IsDir() || !maybeHTMLName(match) {
+			continue
+		}
+
+		if err := walkFile(baseDir, match, fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// walkDir reads all files in directory recursively.
+func walkDir(dir string, fn func(localFile) error) error {
+	return fp.Walk(dir, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() || !maybeHTMLName(filePath) {
+			return nil
+		}
+
+		return walkFile(dir, filePath, fn)
+	})
+}
+
+// walkFile reads a single file and passes it with path that relative to base dir.
+func walkFile(baseDir string, filePath string, fn func(localFile) error) error {
+	relPath, err := fp.Rel(baseDir, filePath)
+	if err != nil {
+		relPath = fp.Base(filePath)
+	}
+
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+
+	return fn(localFile{
+		Path:    fp.ToSlash(relPath),
+		Content: content,
+	})
+}
+
+// walkReader reads the content of reader, which can be a tar archive, zip archive or a single
+// file, optionally compressed with gzip. The format is detected by checking its content.
+func walkReader(r io.Reader, name string, fn func(localFile) error) error {
+	reader := bufio.NewReaderSize(r, 512)
+	magic, _ := reader.Peek(262)
+
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		gzReader, err := gzip.NewReader(reader)
+		if err != nil {
+			return err
+		}
+		defer gzReader.Close()
+
+		name = strings.TrimSuffix(name, fp.Ext(name))
+		return walkReader(gzReader, name, fn)
+
+	case bytes.HasPrefix(magic, []byte("PK\x03\x04")):
+		// Zip needs random access, so the entire archive is loaded into memory
+		content, err := io.ReadAll(reader)
+		if err != nil {
+			return err
+		}
+
+		zipReader, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+		if err != nil {
+			return err
+		}
+
+		return walkZip(zipReader, fn)
+
+	case len(magic) >= 262 && string(magic[257:262]) == "ustar":
+		return walkTar(tar.NewReader(reader), fn)
+
+	default:
+		content, err := io.ReadAll(reader)
+		if err != nil {
+			return err
+		}
+
+		return fn(localFile{Path: name, Content: content})
+	}
+}
+
+// walkTar reads all regular files in tar archive.
+func walkTar(reader *tar.Reader, fn func(localFile) error) error {
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if header.Typeflag != tar.TypeReg || !maybeHTMLName(header.Name) {
+			continue
+		}
+
+		content, err := io.ReadAll(reader)
+		if err != nil {
+			return err
+		}
+
+		err = fn(localFile{
+			Path:    archivePath(header.Name),
+			Content: content,
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// walkZipFile reads all files in zip archive from disk.
+func walkZipFile(zipPath string, fn func(localFile) error) error {
+	zipReader, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return err
+	}
+	defer zipReader.Close()
+
+	return walkZip(&zipReader.Reader, fn)
+}
+
+// walkZip reads all files in zip archive.
+func walkZip(reader *zip.Reader, fn func(localFile) error) error {
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() || !maybeHTMLName(file.Name) {
+			continue
+		}
+
+		content, err := readZipFile(file)
+		if err != nil {
+			return err
+		}
+
+		err = fn(localFile{
+			Path:    archivePath(file.Name),
+			Content: content,
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func readZipFile(file *zip.File) ([]byte, error) {
+	r, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// archivePath cleans the path of file within archive, to make sure it
+// can't go outside of the output directory (e.g. "../../etc/passwd").
+func archivePath(name string) string {
+	name = strings.ReplaceAll(name, "\\", "/")
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
+
+// globBaseDir returns the directory part of glob pattern that doesn't contain any wildcard.
+func globBaseDir(pattern string) string {
+	idx := strings.IndexAny(pattern, "*?[")
+	if idx < 0 {
+		return fp.Dir(pattern)
+	}
+
+	return fp.Dir(pattern[:idx+1])
+}
+
+// maybeHTMLName checks if file might be HTML by its name, i.e. it has HTML extension,
+// it doesn't have any extension or its extension is not known.
+func maybeHTMLName(name string) bool {
+	ext := strings.ToLower(fp.Ext(name))
+	if _, isHTML := htmlExtensions[ext]; isHTML || ext == "" {
+		return true
+	}
+
+	return mime.TypeByExtension(ext) == ""
+}
+
+// isHTMLFile checks if file is HTML, either by its extension or by sniffing its content.
+func isHTMLFile(file localFile) bool {
+	ext := strings.ToLower(path.Ext(file.Path))
+	if _, isHTML := htmlExtensions[ext]; isHTML {
+		return true
+	}
+
+	return strings.Contains(http.DetectContentType(file.Content), "text/html")
+}
@@ -0,0 +1,128 @@
+// This file is part of go-trafilatura, Go package for extracting readable
+// content, comments and metadata from a web page. Source available in
+// <https://github.com/markusmobius/go-trafilatura>.
+// Copyright (C) 2021 Markus Mobius
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or (at your
+// option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+// or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License
+// for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_archivePath(t *testing.T) {
+	assert.Equal(t, "a/b.html", archivePath("a/b.html"))
+	assert.Equal(t, "a/b.html", archivePath("./a//b.html"))
+	assert.Equal(t, "a/b.html", archivePath("a\\b.html"))
+	assert.Equal(t, "etc/passwd", archivePath("../../etc/passwd"))
+	assert.Equal(t, "etc/passwd", archivePath("/etc/passwd"))
+	assert.Equal(t, "b.html", archivePath("a/../../b.html"))
+}
+
+func Test_globBaseDir(t *testing.T) {
+	assert.Equal(t, "dumps", globBaseDir("dumps/*.html"))
+	assert.Equal(t, "dumps", globBaseDir("dumps/page-?.html"))
+	assert.Equal(t, "dumps/2021", globBaseDir("dumps/2021/[ab]*/*.html"))
+	assert.Equal(t, ".", globBaseDir("*.html"))
+	assert.Equal(t, filepath.Join("dumps", "2021"), globBaseDir(filepath.Join("dumps", "2021", "a.html")))
+}
+
+func Test_walkReader(t *testing.T) {
+	files := map[string]string{
+		"index.html":         "<html>index</html>",
+		"../outside.html":    "<html>outside</html>",
+		"sub/page.htm":       "<html>page</html>",
+		"sub/style.css":      "body {}",
+		"sub/dir/":           "",
+		"/absolute/abs.html": "<html>abs</html>",
+	}
+
+	expected := map[string]string{
+		"index.html":        "<html>index</html>",
+		"outside.html":      "<html>outside</html>",
+		"sub/page.htm":      "<html>page</html>",
+		"absolute/abs.html": "<html>abs</html>",
+	}
+
+	walk := func(content []byte, name string) (map[string]string, []string) {
+		var names []string
+		result := make(map[string]string)
+		err := walkReader(bytes.NewReader(content), name, func(file localFile) error {
+			names = append(names, file.Path)
+			result[file.Path] = string(file.Content)
+			return nil
+		})
+		assert.Nil(t, err)
+		return result, names
+	}
+
+	// Tar archive, plain and compressed
+	tarBuffer := bytes.NewBuffer(nil)
+	tw := tar.NewWriter(tarBuffer)
+	for name, content := range files {
+		header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		if content == "" {
+			header.Typeflag = tar.TypeDir
+		}
+		assert.Nil(t, tw.WriteHeader(header))
+		_, err := tw.Write([]byte(content))
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, tw.Close())
+
+	result, _ := walk(tarBuffer.Bytes(), "pages.tar")
+	assert.Equal(t, expected, result)
+
+	result, _ = walk(gzipBytes(t, tarBuffer.Bytes()), "pages.tar.gz")
+	assert.Equal(t, expected, result)
+
+	// Zip archive
+	zipBuffer := bytes.NewBuffer(nil)
+	zw := zip.NewWriter(zipBuffer)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		assert.Nil(t, err)
+		_, err = w.Write([]byte(content))
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, zw.Close())
+
+	result, _ = walk(zipBuffer.Bytes(), "pages.zip")
+	assert.Equal(t, expected, result)
+
+	// Single file, where the name is used as it is except for gzip extension
+	result, names := walk([]byte("<html>single</html>"), "stdin")
+	assert.Equal(t, []string{"stdin"}, names)
+	assert.Equal(t, "<html>single</html>", result["stdin"])
+
+	_, names = walk(gzipBytes(t, []byte("<html>single</html>")), "page.html.gz")
+	assert.Equal(t, []string{"page.html"}, names)
+}
+
+func gzipBytes(t *testing.T, content []byte) []byte {
+	buffer := bytes.NewBuffer(nil)
+	gz := gzip.NewWriter(buffer)
+	_, err := gz.Write(content)
+	assert.Nil(t, err)
+	assert.Nil(t, gz.Close())
+	return buffer.Bytes()
+}
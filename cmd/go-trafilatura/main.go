@@ -21,6 +21,7 @@ package main
 import (
 	"bytes"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
 	"mime"
@@ -56,22 +57,32 @@ func main() {
 
 	// Register persistent flags
 	flags := rootCmd.PersistentFlags()
-	flags.StringP("format", "f", "", "output format for the extract result, either 'html' (default), 'txt' or 'json'")
+	flags.StringP("format", "f", "", "output format for the extract result, either 'html' (default), 'txt', 'json', 'md' or 'chunks'")
+	flags.Int("chunk-size", 1000, "max size of each chunk for 'chunks' output")
+	flags.Int("chunk-overlap", 0, "size of overlapping text between chunks for 'chunks' output")
+	flags.Bool("chunk-tokens", false, "measure chunk size in approximate tokens instead of characters")
+	flags.Bool("toc", false, "add table of contents to 'html' and 'md' output")
+	flags.Int("wrap", 0, "wrap lines of 'txt' output at the specified width (default no wrapping)")
 	flags.StringP("language", "l", "", "target language (ISO 639-1 codes)")
 	flags.Bool("no-fallback", false, "disable fallback extraction using readability and dom-distiller")
 	flags.Bool("no-comments", false, "exclude comments  extraction result")
 	flags.Bool("no-tables", false, "include tables in extraction result")
 	flags.Bool("images", false, "include images in extraction result (experimental)")
 	flags.Bool("links", false, "keep links in extraction result (experimental)")
+	flags.Bool("media-placeholders", false, "leave placeholders for embedded video, audio and social posts in extraction result")
 	flags.Bool("deduplicate", false, "filter out duplicate segments and sections")
 	flags.Bool("has-metadata", false, "only output documents with title, URL and date")
+	flags.Int64("max-input-size", 0, "max size of each page in MB (default unlimited)")
+	flags.Int("max-nodes", 0, "max number of nodes in each page (default unlimited)")
+	flags.Int("max-depth", 0, "max nesting depth of each page (default unlimited)")
+	flags.Int("max-duration", 0, "max duration for extracting each page in seconds (default unlimited)")
 	flags.BoolP("verbose", "v", false, "enable log message")
 	flags.IntP("timeout", "t", 30, "timeout for downloading web page in seconds")
 	flags.Bool("skip-tls", false, "skip X.509 (TLS) certificate verification")
 	flags.StringP("user-agent", "u", defaultUserAgent, "set custom user agent")
 
 	// Add sub commands
-	rootCmd.AddCommand(batchCmd(), sitemapCmd(), feedCmd())
+	rootCmd.AddCommand(batchCmd(), sitemapCmd(), feedCmd(), reportCmd(), warcCmd(), filesCmd(), serveCmd(), evalCmd())
 
 	// Execute
 	err := rootCmd.Execute()
@@ -148,25 +159,105 @@ func processFile(path string, opts trafilatura.Options) (*trafilatura.ExtractRes
 }
 
 func processURL(client *http.Client, userAgent string, url *nurl.URL, opts trafilatura.Options) (*trafilatura.ExtractResult, error) {
+	// Unlike batch download, the page is still extracted when server responds
+	// with unsuccessful status code, as long as it's HTML.
+	page, err := fetchPage(client, userAgent, url, nil, opts.MaxInputBytes)
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) {
+		err = checkHtmlPage(page)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return extractPage(page, opts)
+}
+
+// fetchedPage is a HTML page that downloaded from server.
+type fetchedPage struct {
+	URL        *nurl.URL
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// errNotModified is returned when server responds to conditional request with 304 Not Modified.
+var errNotModified = errors.New("page is not modified")
+
+// errPageTooLarge is returned when the downloaded page is larger than the allowed size.
+var errPageTooLarge = errors.New("page is too large")
+
+// fetchPage downloads the URL and make sure it's a HTML page. If server responds with
+// unsuccessful status code or non-HTML content, the page is still returned along with
+// the error. If maxSize is positive, the page that larger than it is rejected without
+// reading the rest of its body.
+func fetchPage(client *http.Client, userAgent string, url *nurl.URL, header http.Header, maxSize int64) (*fetchedPage, error) {
 	// Download URL
 	strURL := url.String()
 	logrus.Println("downloading", strURL)
 
-	resp, err := download(client, userAgent, strURL)
+	resp, err := downloadWithHeader(client, userAgent, strURL, header)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
+	page := &fetchedPage{
+		URL:        url,
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+	}
+
+	// Server doesn't send body for unmodified page
+	if resp.StatusCode == http.StatusNotModified {
+		return page, errNotModified
+	}
+
+	// Read the page. It's done before checking the response, so the unsuccessful
+	// and non-HTML responses can still be archived.
+	var body io.Reader = resp.Body
+	if maxSize > 0 {
+		body = io.LimitReader(resp.Body, maxSize+1)
+	}
+
+	page.Body, err = io.ReadAll(body)
+	if err != nil {
+		return page, err
+	}
+
+	if maxSize > 0 && int64(len(page.Body)) > maxSize {
+		page.Body = page.Body[:maxSize]
+		return page, errPageTooLarge
+	}
+
+	// Make sure download succeed
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return page, newHttpStatusError(resp)
+	}
+
 	// Make sure it's html
-	contentType := resp.Header.Get("Content-Type")
+	if err := checkHtmlPage(page); err != nil {
+		return page, err
+	}
+
+	return page, nil
+}
+
+// checkHtmlPage makes sure the downloaded page is a HTML page.
+func checkHtmlPage(page *fetchedPage) error {
+	contentType := page.Header.Get("Content-Type")
 	if !strings.Contains(contentType, "text/html") {
-		return nil, fmt.Errorf("page is not html: \"%s\"", contentType)
+		return fmt.Errorf("page is not html: \"%s\"", contentType)
 	}
+	return nil
+}
 
-	// Extract
-	opts.OriginalURL = url
-	result, err := trafilatura.Extract(resp.Body, opts)
+// extractPage extracts content from the downloaded page.
+func extractPage(page *fetchedPage, opts trafilatura.Options) (*trafilatura.ExtractResult, error) {
+	opts.OriginalURL = page.URL
+	opts.ContentType = page.Header.Get("Content-Type")
+	result, err := trafilatura.Extract(bytes.NewReader(page.Body), opts)
 	if err != nil {
 		return nil, err
 	}
@@ -184,9 +275,18 @@ func createExtractorOptions(cmd *cobra.Command) trafilatura.Options {
 	opts.ExcludeTables, _ = flags.GetBool("no-tables")
 	opts.IncludeImages, _ = flags.GetBool("images")
 	opts.IncludeLinks, _ = flags.GetBool("links")
+	opts.MediaPlaceholders, _ = flags.GetBool("media-placeholders")
 	opts.Deduplicate, _ = flags.GetBool("deduplicate")
 	opts.HasEssentialMetadata, _ = flags.GetBool("has-metadata")
+	opts.MaxNodes, _ = flags.GetInt("max-nodes")
+	opts.MaxDepth, _ = flags.GetInt("max-depth")
 	opts.EnableLog, _ = flags.GetBool("verbose")
+
+	maxInputSize, _ := flags.GetInt64("max-input-size")
+	opts.MaxInputBytes = maxInputSize * 1024 * 1024
+
+	maxDuration, _ := flags.GetInt("max-duration")
+	opts.MaxDuration = time.Duration(maxDuration) * time.Second
 	return opts
 }
 
@@ -206,11 +306,22 @@ func createHttpClient(cmd *cobra.Command) *http.Client {
 }
 
 func download(client *http.Client, userAgent string, url string) (*http.Response, error) {
+	return downloadWithHeader(client, userAgent, url, nil)
+}
+
+// downloadWithHeader downloads the URL with additional request header.
+func downloadWithHeader(client *http.Client, userAgent string, url string, header http.Header) (*http.Response, error) {
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
 
+	for key, values := range header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+
 	req.Header.Set("User-Agent", userAgent)
 	resp, err := client.Do(req)
 	if err != nil {
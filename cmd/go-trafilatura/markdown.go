@@ -0,0 +1,357 @@
+// This file is part of go-trafilatura, Go package for extracting readable
+// content, comments and metadata from a web page. Source available in
+// <https://github.com/markusmobius/go-trafilatura>.
+// Copyright (C) 2021 Markus Mobius
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or (at your
+// option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+// or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License
+// for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-shiori/dom"
+	"github.com/markusmobius/go-trafilatura"
+	"golang.org/x/net/html"
+)
+
+var (
+	rxMarkdownSpaces   = regexp.MustCompile(`[ \t\r\n]+`)
+	rxMarkdownNewlines = regexp.MustCompile(`\n{3,}`)
+	rxMarkdownTrailing = regexp.MustCompile(`[ \t]+\n`)
+)
+
+func writeMarkdown(w io.Writer, result *trafilatura.ExtractResult, withTOC bool) error {
+	// The footnote markers will be replaced in the content
+	result = cloneResult(result)
+	buffer := &strings.Builder{}
+
+	// Put metadata as front matter
+	metadata := [][2]string{
+		{"title", result.Metadata.Title},
+		{"author", result.Metadata.Author},
+		{"url", result.Metadata.URL},
+		{"hostname", result.Metadata.Hostname},
+		{"description", result.Metadata.Description},
+		{"sitename", result.Metadata.Sitename},
+		{"categories", strings.Join(result.Metadata.Categories, ", ")},
+		{"tags", strings.Join(result.Metadata.Tags, "; ")},
+		{"license", result.Metadata.License},
+	}
+
+	if !result.Metadata.Date.IsZero() {
+		metadata = append(metadata, [2]string{"date", result.Metadata.Date.Format("2006-01-02")})
+	}
+
+	buffer.WriteString("---\n")
+	for _, field := range metadata {
+		if field[1] != "" {
+			fmt.Fprintf(buffer, "%s: %s\n", field[0], strconv.Quote(field[1]))
+		}
+	}
+	buffer.WriteString("---\n\n")
+
+	// Put table of contents
+	if withTOC {
+		if toc := markdownTOC(result.Sections(), 0); toc != "" {
+			buffer.WriteString(toc + "\n\n")
+		}
+	}
+
+	// Convert footnote markers into Markdown footnote references
+	keys := markdownFootnoteKeys(result.Footnotes)
+	for marker, idx := range footnoteMarkers(result.ContentNode, result.Footnotes) {
+		if marker.Parent != nil {
+			reference := dom.CreateTextNode("[^" + keys[idx] + "]")
+			marker.Parent.InsertBefore(reference, marker)
+			marker.Parent.RemoveChild(marker)
+		}
+	}
+
+	// Put content, footnotes and comments
+	buffer.WriteString(markdownFromNode(result.ContentNode))
+	for i, footnote := range result.Footnotes {
+		if i == 0 {
+			buffer.WriteString("\n")
+		}
+		prefix := "[^" + keys[i] + "]: "
+		buffer.WriteString("\n" + markdownIndent(footnote.Text, prefix, "    "))
+	}
+
+	if comments := markdownFromNode(result.CommentsNode); comments != "" {
+		buffer.WriteString("\n\n---\n\n")
+		buffer.WriteString(comments)
+	}
+
+	buffer.WriteString("\n")
+	_, err := io.WriteString(w, buffer.String())
+	return err
+}
+
+// markdownTOC creates nested list that links to the sections.
+func markdownTOC(sections []*trafilatura.Section, depth int) string {
+	var lines []string
+	for _, section := range sections {
+		childDepth := depth
+		if section.Heading != nil {
+			indent := strings.Repeat("  ", depth)
+			title := strings.NewReplacer("[", "\\[", "]", "\\]").Replace(section.Title)
+			lines = append(lines, indent+"- ["+title+"](#"+section.Anchor+")")
+			childDepth++
+		}
+
+		if children := markdownTOC(section.Children, childDepth); children != "" {
+			lines = append(lines, children)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// markdownFromNode converts the extracted node into Markdown.
+func markdownFromNode(node *html.Node) string {
+	if node == nil {
+		return ""
+	}
+
+	str := markdownChildren(node)
+	str = rxMarkdownTrailing.ReplaceAllString(str, "\n")
+	str = rxMarkdownNewlines.ReplaceAllString(str, "\n\n")
+	return strings.TrimSpace(str)
+}
+
+func markdownChildren(node *html.Node) string {
+	buffer := &strings.Builder{}
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		buffer.WriteString(markdownNode(child))
+	}
+	return buffer.String()
+}
+
+func markdownNode(node *html.Node) string {
+	if node.Type == html.TextNode {
+		return rxMarkdownSpaces.ReplaceAllString(node.Data, " ")
+	}
+
+	if node.Type != html.ElementNode {
+		return ""
+	}
+
+	switch tagName := dom.TagName(node); tagName {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		level, _ := strconv.Atoi(tagName[1:])
+		return "\n\n" + strings.Repeat("#", level) + " " + markdownInline(node) + "\n\n"
+
+	case "br":
+		return "\\\n"
+
+	case "hr":
+		return "\n\n---\n\n"
+
+	case "b", "strong":
+		return markdownWrap(markdownChildren(node), "**")
+
+	case "i", "em":
+		return markdownWrap(markdownChildren(node), "_")
+
+	case "del", "s", "strike":
+		return markdownWrap(markdownChildren(node), "~~")
+
+	case "code", "kbd", "samp", "tt":
+		return markdownWrap(dom.TextContent(node), "`")
+
+	case "a":
+		text := markdownChildren(node)
+		href := dom.GetAttribute(node, "href")
+		if href == "" || strings.TrimSpace(text) == "" {
+			return text
+		}
+		return "[" + strings.TrimSpace(text) + "](" + href + ")"
+
+	case "img":
+		src := dom.GetAttribute(node, "src")
+		if src == "" {
+			return ""
+		}
+		return "![" + dom.GetAttribute(node, "alt") + "](" + src + ")"
+
+	case "pre":
+		var language string
+		if code := dom.QuerySelector(node, "code"); code != nil {
+			language = strings.TrimPrefix(dom.GetAttribute(code, "class"), "language-")
+		}
+
+		code := strings.Trim(dom.TextContent(node), "\n")
+		fence := "```"
+		for strings.Contains(code, fence) {
+			fence += "`"
+		}
+		return "\n\n" + fence + language + "\n" + code + "\n" + fence + "\n\n"
+
+	case "blockquote", "q":
+		content := strings.TrimSpace(markdownFromNode(node))
+		if content == "" {
+			return ""
+		}
+		return "\n\n" + markdownIndent(content, "> ", "> ") + "\n\n"
+
+	case "ul", "ol", "dl":
+		return "\n\n" + markdownList(node) + "\n\n"
+
+	case "li", "dd", "dt":
+		return "\n\n" + markdownIndent(markdownFromNode(node), "- ", "  ") + "\n\n"
+
+	case "table":
+		return "\n\n" + markdownTable(node) + "\n\n"
+
+	default:
+		if _, isBlock := markdownBlockTags[tagName]; isBlock {
+			return "\n\n" + strings.TrimSpace(markdownChildren(node)) + "\n\n"
+		}
+		return markdownChildren(node)
+	}
+}
+
+var markdownBlockTags = sliceToMap("p", "div", "section", "article", "main", "body",
+	"header", "footer", "details", "summary", "figure", "figcaption")
+
+// markdownInline converts the node into single line of Markdown.
+func markdownInline(node *html.Node) string {
+	str := markdownChildren(node)
+	str = strings.ReplaceAll(str, "\\\n", " ")
+	str = rxMarkdownSpaces.ReplaceAllString(str, " ")
+	return strings.TrimSpace(str)
+}
+
+// markdownWrap wraps the text with the marker, while keeping the surrounding spaces outside.
+func markdownWrap(text string, marker string) string {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		return text
+	}
+
+	start := text[:strings.Index(text, trimmed)]
+	end := text[len(start)+len(trimmed):]
+	return start + marker + trimmed + marker + end
+}
+
+// markdownIndent prefixes the first line with first prefix, and the rest with other prefix.
+func markdownIndent(text string, firstPrefix string, otherPrefix string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		switch {
+		case i == 0:
+			lines[i] = firstPrefix + line
+		case line != "":
+			lines[i] = otherPrefix + line
+		default:
+			lines[i] = strings.TrimRight(otherPrefix, " ")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func markdownList(list *html.Node) string {
+	var items []string
+	number := 1
+	if start, err := strconv.Atoi(dom.GetAttribute(list, "start")); err == nil {
+		number = start
+	}
+
+	for child := list.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type != html.ElementNode {
+			continue
+		}
+
+		content := markdownFromNode(child)
+		if content == "" {
+			continue
+		}
+
+		switch {
+		case dom.TagName(list) == "ol":
+			marker := strconv.Itoa(number) + ". "
+			items = append(items, markdownIndent(content, marker, strings.Repeat(" ", len(marker))))
+			number++
+		case dom.TagName(child) == "dt":
+			items = append(items, markdownInline(child))
+		case dom.TagName(child) == "dd":
+			items = append(items, markdownIndent(content, ": ", "  "))
+		default:
+			items = append(items, markdownIndent(content, "- ", "  "))
+		}
+	}
+
+	return strings.Join(items, "\n")
+}
+
+func markdownTable(table *html.Node) string {
+	var rows [][]string
+	nColumns := 0
+	for _, tr := range dom.GetElementsByTagName(table, "tr") {
+		var cells []string
+		for child := tr.FirstChild; child != nil; child = child.NextSibling {
+			if tag := dom.TagName(child); tag == "td" || tag == "th" {
+				cell := strings.ReplaceAll(markdownInline(child), "|", "\\|")
+				cells = append(cells, cell)
+			}
+		}
+
+		if len(cells) > nColumns {
+			nColumns = len(cells)
+		}
+		rows = append(rows, cells)
+	}
+
+	if nColumns == 0 {
+		return ""
+	}
+
+	lines := make([]string, 0, len(rows)+1)
+	for i, cells := range rows {
+		for len(cells) < nColumns {
+			cells = append(cells, "")
+		}
+		lines = append(lines, "| "+strings.Join(cells, " | ")+" |")
+
+		// Markdown table requires header, so the first row is used as it
+		if i == 0 {
+			lines = append(lines, "|"+strings.Repeat(" --- |", nColumns))
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// markdownFootnoteKeys returns the key of footnotes in Markdown, which is the label of
+// footnote. If the label is used by several footnotes, e.g. the numbering restarts in
+// each section, the repeated one is suffixed with its position.
+func markdownFootnoteKeys(footnotes []trafilatura.Footnote) []string {
+	keys := make([]string, len(footnotes))
+	usedKeys := make(map[string]struct{})
+	for i, footnote := range footnotes {
+		key := footnote.Label
+		if _, used := usedKeys[key]; used {
+			key = fmt.Sprintf("%s-%d", footnote.Label, i+1)
+		}
+
+		keys[i] = key
+		usedKeys[key] = struct{}{}
+	}
+	return keys
+}
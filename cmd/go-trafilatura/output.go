@@ -23,6 +23,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
 
 	"github.com/go-shiori/dom"
@@ -40,6 +41,10 @@ func outputExt(cmd *cobra.Command) string {
 		return ".txt"
 	case "json":
 		return ".json"
+	case "chunks":
+		return ".jsonl"
+	case "md", "markdown":
+		return ".md"
 	default:
 		return ".html"
 	}
@@ -50,26 +55,45 @@ func writeOutput(w io.Writer, result *trafilatura.ExtractResult, cmd *cobra.Comm
 
 	switch outputFormat {
 	case "txt":
-		return writeText(w, result)
+		width, _ := cmd.Flags().GetInt("wrap")
+		return writeText(w, result, width)
 	case "json":
 		return writeJSON(w, result)
+	case "chunks":
+		return writeChunks(w, result, chunkOptions(cmd))
+	case "md", "markdown":
+		withTOC, _ := cmd.Flags().GetBool("toc")
+		return writeMarkdown(w, result, withTOC)
 	default:
-		return writeHTML(w, result)
+		withTOC, _ := cmd.Flags().GetBool("toc")
+		return writeHTML(w, result, withTOC)
 	}
 }
 
-func writeText(w io.Writer, result *trafilatura.ExtractResult) error {
-	buffer := bytes.NewBuffer(nil)
-	buffer.WriteString(result.ContentText)
+func writeText(w io.Writer, result *trafilatura.ExtractResult, width int) error {
+	opts := trafilatura.TextOptions{Width: width}
+	text := trafilatura.RenderText(result.ContentNode, opts)
 
-	if result.CommentsText != "" {
-		if buffer.Len() > 0 {
-			buffer.WriteString(" ")
+	if len(result.Footnotes) > 0 {
+		lines := make([]string, len(result.Footnotes))
+		for i, footnote := range result.Footnotes {
+			lines[i] = footnote.Marker + " " + footnote.Text
 		}
-		buffer.WriteString(result.ContentText)
+
+		if text != "" {
+			text += "\n\n"
+		}
+		text += strings.Join(lines, "\n")
+	}
+
+	if comments := trafilatura.RenderText(result.CommentsNode, opts); comments != "" {
+		if text != "" {
+			text += "\n\n"
+		}
+		text += comments
 	}
 
-	_, err := io.Copy(w, buffer)
+	_, err := io.WriteString(w, text+"\n")
 	return err
 }
 
@@ -78,7 +102,49 @@ func writeJSON(w io.Writer, result *trafilatura.ExtractResult) error {
 	return json.NewEncoder(w).Encode(data)
 }
 
-func writeHTML(w io.Writer, result *trafilatura.ExtractResult) error {
+// writeChunks writes the chunks of extracted content as JSON Lines, one chunk per line.
+func writeChunks(w io.Writer, result *trafilatura.ExtractResult, opts trafilatura.ChunkOptions) error {
+	metadata := jsonMetadata(result.Metadata)
+	encoder := json.NewEncoder(w)
+
+	for _, chunk := range result.Chunks(opts) {
+		headings := chunk.Headings
+		if headings == nil {
+			headings = []string{}
+		}
+
+		err := encoder.Encode(map[string]interface{}{
+			"index":    chunk.Index,
+			"text":     chunk.Text,
+			"size":     chunk.Size,
+			"headings": headings,
+			"metadata": metadata,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func chunkOptions(cmd *cobra.Command) trafilatura.ChunkOptions {
+	flags := cmd.Flags()
+	maxSize, _ := flags.GetInt("chunk-size")
+	overlap, _ := flags.GetInt("chunk-overlap")
+	countTokens, _ := flags.GetBool("chunk-tokens")
+
+	return trafilatura.ChunkOptions{
+		MaxSize:     maxSize,
+		Overlap:     overlap,
+		CountTokens: countTokens,
+	}
+}
+
+func writeHTML(w io.Writer, result *trafilatura.ExtractResult, withTOC bool) error {
+	// The content will be modified and moved into the new document
+	result = cloneResult(result)
+
 	// Create base document
 	doc, _ := html.Parse(bytes.NewBuffer(nil))
 	head := dom.QuerySelector(doc, "head")
@@ -125,6 +191,15 @@ func writeHTML(w io.Writer, result *trafilatura.ExtractResult) error {
 	dom.SetAttribute(meta, "name", "license")
 	dom.SetAttribute(meta, "content", result.Metadata.License)
 
+	// Put table of contents
+	if withTOC {
+		if toc := htmlTOC(result.Sections()); toc != nil {
+			nav := etree.SubElement(body, "nav")
+			dom.SetAttribute(nav, "id", "table-of-contents")
+			dom.AppendChild(nav, toc)
+		}
+	}
+
 	// Put content
 	content := result.ContentNode
 	if content != nil {
@@ -133,6 +208,33 @@ func writeHTML(w io.Writer, result *trafilatura.ExtractResult) error {
 		dom.AppendChild(body, content)
 	}
 
+	// Put footnotes and link the markers to them
+	if len(result.Footnotes) > 0 {
+		for marker, idx := range footnoteMarkers(content, result.Footnotes) {
+			footnote := result.Footnotes[idx]
+			link := marker
+			if dom.TagName(marker) != "a" {
+				link = etree.Element("a")
+				etree.SetText(link, footnote.Marker)
+				dom.SetTextContent(marker, "")
+				dom.AppendChild(marker, link)
+			}
+			dom.SetAttribute(link, "href", "#"+footnote.ID)
+		}
+
+		section := etree.SubElement(body, "section")
+		dom.SetAttribute(section, "id", "footnotes")
+		list := etree.SubElement(section, "ol")
+		for _, footnote := range result.Footnotes {
+			item := etree.SubElement(list, "li")
+			dom.SetAttribute(item, "id", footnote.ID)
+			if _, err := strconv.Atoi(footnote.Label); err == nil {
+				dom.SetAttribute(item, "value", footnote.Label)
+			}
+			etree.SetText(item, footnote.Text)
+		}
+	}
+
 	// Put comments
 	comments := result.CommentsNode
 	if comments != nil {
@@ -146,28 +248,154 @@ func writeHTML(w io.Writer, result *trafilatura.ExtractResult) error {
 	return err
 }
 
+// footnoteMarkers returns the elements in the content that mark the footnotes, along
+// with the index of their footnote. The marker is matched by its link to the footnote
+// id, since the numbering might restart in each section. When the links are not kept,
+// the marker is <sup> that only matched by its text if it's used by a single footnote.
+func footnoteMarkers(root *html.Node, footnotes []trafilatura.Footnote) map[*html.Node]int {
+	markers := make(map[*html.Node]int)
+	if root == nil {
+		return markers
+	}
+
+	footnoteByID := make(map[string]int)
+	footnoteByMarker := make(map[string]int)
+	for i, footnote := range footnotes {
+		if _, exist := footnoteByID[footnote.ID]; !exist {
+			footnoteByID[footnote.ID] = i
+		}
+
+		if _, exist := footnoteByMarker[footnote.Marker]; exist {
+			footnoteByMarker[footnote.Marker] = -1
+		} else {
+			footnoteByMarker[footnote.Marker] = i
+		}
+	}
+
+	for _, element := range dom.QuerySelectorAll(root, "sup, a") {
+		if dom.TagName(element) == "a" {
+			href := dom.GetAttribute(element, "href")
+			if idx, exist := footnoteByID[strings.TrimPrefix(href, "#")]; exist && strings.HasPrefix(href, "#") {
+				markers[element] = idx
+			}
+			continue
+		}
+
+		if dom.QuerySelector(element, "a") != nil {
+			continue
+		}
+
+		if idx, exist := footnoteByMarker[strings.TrimSpace(dom.TextContent(element))]; exist && idx >= 0 {
+			markers[element] = idx
+		}
+	}
+
+	return markers
+}
+
+// cloneResult returns copy of the result with cloned content and comments, so they
+// can be modified while writing the result without changing the original.
+func cloneResult(result *trafilatura.ExtractResult) *trafilatura.ExtractResult {
+	clone := *result
+	if result.ContentNode != nil {
+		clone.ContentNode = dom.Clone(result.ContentNode, true)
+	}
+
+	if result.CommentsNode != nil {
+		clone.CommentsNode = dom.Clone(result.CommentsNode, true)
+	}
+
+	return &clone
+}
+
+// htmlTOC creates nested list that links to the sections. It also puts
+// the anchor as id of the section headings.
+func htmlTOC(sections []*trafilatura.Section) *html.Node {
+	list := etree.Element("ul")
+	for _, section := range sections {
+		children := htmlTOC(section.Children)
+		if section.Heading == nil {
+			continue
+		}
+
+		dom.SetAttribute(section.Heading, "id", section.Anchor)
+		item := etree.SubElement(list, "li")
+		link := etree.SubElement(item, "a")
+		dom.SetAttribute(link, "href", "#"+section.Anchor)
+		etree.SetText(link, section.Title)
+
+		if children != nil {
+			dom.AppendChild(item, children)
+		}
+	}
+
+	if len(dom.Children(list)) == 0 {
+		return nil
+	}
+
+	return list
+}
+
 type jsonExtractResult trafilatura.ExtractResult
 
 func (r jsonExtractResult) MarshalJSON() ([]byte, error) {
-	// Convert metadata to map first
-	metadata := map[string]interface{}{
-		"title":       r.Metadata.Title,
-		"author":      r.Metadata.Author,
-		"url":         r.Metadata.URL,
-		"hostname":    r.Metadata.Hostname,
-		"description": r.Metadata.Description,
-		"sitename":    r.Metadata.Sitename,
-		"date":        r.Metadata.Date,
-		"categories":  r.Metadata.Categories,
-		"tags":        r.Metadata.Tags,
-		"license":     r.Metadata.License,
-	}
+	result := r.toMap()
+	return json.Marshal(&result)
+}
 
+// toMap converts the extract result into map, so other fields can be added before it's encoded.
+func (r jsonExtractResult) toMap() map[string]interface{} {
 	// Convert result to map
 	result := map[string]interface{}{
 		"contentHTML": dom.OuterHTML(r.ContentNode),
 		"contentText": r.ContentText,
-		"metadata":    metadata,
+		"metadata":    jsonMetadata(r.Metadata),
+	}
+
+	if r.Encoding != "" {
+		result["encoding"] = r.Encoding
+	}
+
+	if len(r.CodeBlocks) > 0 {
+		codeBlocks := make([]map[string]interface{}, len(r.CodeBlocks))
+		for i, block := range r.CodeBlocks {
+			codeBlocks[i] = map[string]interface{}{
+				"language": block.Language,
+				"code":     block.Code,
+			}
+		}
+		result["codeBlocks"] = codeBlocks
+	}
+
+	if len(r.Media) > 0 {
+		media := make([]map[string]interface{}, len(r.Media))
+		for i, item := range r.Media {
+			media[i] = map[string]interface{}{
+				"kind":        item.Kind,
+				"provider":    item.Provider,
+				"source":      item.Source,
+				"title":       item.Title,
+				"caption":     item.Caption,
+				"position":    item.Position,
+				"before":      item.Before,
+				"after":       item.After,
+				"placeholder": item.Placeholder,
+			}
+		}
+		result["media"] = media
+	}
+
+	if len(r.Footnotes) > 0 {
+		footnotes := make([]map[string]interface{}, len(r.Footnotes))
+		for i, footnote := range r.Footnotes {
+			footnotes[i] = map[string]interface{}{
+				"id":     footnote.ID,
+				"label":  footnote.Label,
+				"marker": footnote.Marker,
+				"text":   footnote.Text,
+			}
+		}
+		result["footnotes"] = footnotes
 	}
 
 	if r.CommentsNode != nil {
@@ -175,5 +403,42 @@ func (r jsonExtractResult) MarshalJSON() ([]byte, error) {
 		result["commentsHTML"] = dom.OuterHTML(r.CommentsNode)
 	}
 
-	return json.Marshal(&result)
+	if len(r.Comments) > 0 {
+		comments := make([]map[string]interface{}, len(r.Comments))
+		for i, comment := range r.Comments {
+			item := map[string]interface{}{
+				"id":        comment.ID,
+				"parentId":  comment.ParentID,
+				"depth":     comment.Depth,
+				"author":    comment.Author,
+				"text":      comment.Text,
+				"permalink": comment.Permalink,
+			}
+
+			if !comment.Date.IsZero() {
+				item["date"] = comment.Date
+			}
+
+			comments[i] = item
+		}
+		result["comments"] = comments
+	}
+
+	return result
+}
+
+// jsonMetadata converts the metadata into map.
+func jsonMetadata(m trafilatura.Metadata) map[string]interface{} {
+	return map[string]interface{}{
+		"title":       m.Title,
+		"author":      m.Author,
+		"url":         m.URL,
+		"hostname":    m.Hostname,
+		"description": m.Description,
+		"sitename":    m.Sitename,
+		"date":        m.Date,
+		"categories":  m.Categories,
+		"tags":        m.Tags,
+		"license":     m.License,
+	}
 }
@@ -0,0 +1,60 @@
+// This file is part of go-trafilatura, Go package for extracting readable
+// content, comments and metadata from a web page. Source available in
+// <https://github.com/markusmobius/go-trafilatura>.
+// Copyright (C) 2021 Markus Mobius
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or (at your
+// option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+// or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License
+// for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func reportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "report [flags] [state-file]",
+		Short: "Print summary of crawl state",
+		Long: "Print summary of crawl state that saved using \"--state\" flag in batch, sitemap\n" +
+			"and feed command. The summary contains number of urls for each status and\n" +
+			"HTTP status code, along with list of failed urls.",
+		Args: cobra.ExactArgs(1),
+		Run:  reportCmdHandler,
+	}
+
+	return cmd
+}
+
+func reportCmdHandler(cmd *cobra.Command, args []string) {
+	records, err := loadCrawlRecords(args[0])
+	if err != nil {
+		logrus.Fatalf("failed to load state: %v", err)
+	}
+
+	summary := summarizeCrawlRecords(records)
+	outputFormat, _ := cmd.Flags().GetString("format")
+	if outputFormat == "json" {
+		err = json.NewEncoder(os.Stdout).Encode(&summary)
+	} else {
+		err = summary.writeText(os.Stdout)
+	}
+
+	if err != nil {
+		logrus.Fatalf("failed to write report: %v", err)
+	}
+}
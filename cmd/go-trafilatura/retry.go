@@ -0,0 +1,138 @@
+// This file is part of go-trafilatura, Go package for extracting readable
+// content, comments and metadata from a web page. Source available in
+// <https://github.com/markusmobius/go-trafilatura>.
+// Copyright (C) 2021 Markus Mobius
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or (at your
+// option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+// or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License
+// for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// maxRetryWait is the longest time we are willing to wait before retrying a download.
+const maxRetryWait = 10 * time.Minute
+
+// httpStatusError is returned when server responds with unsuccessful status code.
+type httpStatusError struct {
+	StatusCode int
+	RetryAfter time.Duration
+}
+
+func newHttpStatusError(resp *http.Response) *httpStatusError {
+	return &httpStatusError{
+		StatusCode: resp.StatusCode,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("server returns status %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// parseRetryAfter parses value of Retry-After header, which could be
+// either number of seconds or a HTTP date.
+func parseRetryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(date); wait > 0 {
+			return wait
+		}
+	}
+
+	return 0
+}
+
+// isRetryableError checks if the error is temporary so the download worth to be retried,
+// i.e. timeout, reset or refused connection, temporary DNS error, "429 Too Many Requests"
+// or server error (5xx). It also returns how long server asked us to wait, if any.
+func isRetryableError(err error) (bool, time.Duration) {
+	if err == nil {
+		return false, 0
+	}
+
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) {
+		retryable := statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= 500
+		return retryable, statusErr.RetryAfter
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTemporary || dnsErr.IsTimeout, 0
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true, 0
+	}
+
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) {
+		return true, 0
+	}
+
+	return false, 0
+}
+
+// retryBackoff returns the exponential delay for the specified attempt (starting
+// from zero), with random jitter to prevent all retries happen at the same time.
+func retryBackoff(base time.Duration, attempt int) time.Duration {
+	if base <= 0 {
+		return 0
+	}
+
+	wait := base << uint(attempt)
+	if wait <= 0 || wait > maxRetryWait {
+		wait = maxRetryWait
+	}
+
+	jitter := time.Duration(rand.Int63n(int64(wait)/2 + 1))
+	return wait/2 + jitter
+}
+
+// sleepContext pauses the current goroutine until the duration passed
+// or the context cancelled, whichever happens first.
+func sleepContext(ctx context.Context, duration time.Duration) error {
+	if duration <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,121 @@
+// This file is part of go-trafilatura, Go package for extracting readable
+// content, comments and metadata from a web page. Source available in
+// <https://github.com/markusmobius/go-trafilatura>.
+// Copyright (C) 2021 Markus Mobius
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or (at your
+// option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+// or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License
+// for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	nurl "net/url"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseRetryAfter(t *testing.T) {
+	future := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	past := time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)
+
+	tests := []struct {
+		value    string
+		min, max time.Duration
+	}{
+		{"", 0, 0},
+		{"120", 2 * time.Minute, 2 * time.Minute},
+		{" 5 ", 5 * time.Second, 5 * time.Second},
+		{"0", 0, 0},
+		{"-10", 0, 0},
+		{"soon", 0, 0},
+		{future, 59 * time.Minute, time.Hour},
+		{past, 0, 0},
+	}
+
+	for _, test := range tests {
+		wait := parseRetryAfter(test.value)
+		assert.True(t, wait >= test.min && wait <= test.max, "%q: %v", test.value, wait)
+	}
+}
+
+func Test_retryBackoff(t *testing.T) {
+	tests := []struct {
+		base     time.Duration
+		attempt  int
+		min, max time.Duration
+	}{
+		{0, 3, 0, 0},
+		{-time.Second, 0, 0, 0},
+		{time.Second, 0, 500 * time.Millisecond, time.Second},
+		{time.Second, 1, time.Second, 2 * time.Second},
+		{time.Second, 3, 4 * time.Second, 8 * time.Second},
+		{time.Second, 20, maxRetryWait / 2, maxRetryWait},
+		{time.Second, 100, maxRetryWait / 2, maxRetryWait},
+	}
+
+	for _, test := range tests {
+		for i := 0; i < 20; i++ {
+			wait := retryBackoff(test.base, test.attempt)
+			assert.True(t, wait >= test.min && wait <= test.max,
+				"base %v, attempt %d: %v", test.base, test.attempt, wait)
+		}
+	}
+}
+
+func Test_isRetryableError(t *testing.T) {
+	urlError := func(err error) error {
+		return &nurl.Error{Op: "Get", URL: "http://example.org", Err: err}
+	}
+
+	opError := func(err error) error {
+		return urlError(&net.OpError{Op: "dial", Net: "tcp", Err: &os.SyscallError{Syscall: "connect", Err: err}})
+	}
+
+	tests := []struct {
+		err        error
+		retryable  bool
+		retryAfter time.Duration
+	}{
+		{nil, false, 0},
+		{errors.New("page is not html"), false, 0},
+		{errNotModified, false, 0},
+		{errPageTooLarge, false, 0},
+		{&httpStatusError{StatusCode: 404}, false, 0},
+		{&httpStatusError{StatusCode: 429, RetryAfter: time.Minute}, true, time.Minute},
+		{fmt.Errorf("wrapped: %w", &httpStatusError{StatusCode: 503}), true, 0},
+		{urlError(context.DeadlineExceeded), true, 0},
+		{opError(syscall.ECONNREFUSED), true, 0},
+		{opError(syscall.ECONNRESET), true, 0},
+		{urlError(&net.DNSError{Err: "server misbehaving", IsTemporary: true}), true, 0},
+		{urlError(&net.DNSError{Err: "i/o timeout", IsTimeout: true}), true, 0},
+		{urlError(&net.DNSError{Err: "no such host", IsNotFound: true}), false, 0},
+		{urlError(errors.New("x509: certificate signed by unknown authority")), false, 0},
+		{urlError(errors.New("stopped after 10 redirects")), false, 0},
+		{opError(errPrivateAddress), false, 0},
+	}
+
+	for _, test := range tests {
+		retryable, retryAfter := isRetryableError(test.err)
+		assert.Equal(t, test.retryable, retryable, "%v", test.err)
+		assert.Equal(t, test.retryAfter, retryAfter, "%v", test.err)
+	}
+}
@@ -0,0 +1,307 @@
+// This file is part of go-trafilatura, Go package for extracting readable
+// content, comments and metadata from a web page. Source available in
+// <https://github.com/markusmobius/go-trafilatura>.
+// Copyright (C) 2021 Markus Mobius
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or (at your
+// option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+// or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License
+// for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	nurl "net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sync/semaphore"
+)
+
+// robotsRule is a single Allow or Disallow line in robots.txt.
+type robotsRule struct {
+	pattern string
+	allow   bool
+}
+
+// defaultRobotsAgent is the product token that used to find our group in robots.txt.
+const defaultRobotsAgent = "go-trafilatura"
+
+// robotsRules is the set of rules in robots.txt that applies to our user agent.
+type robotsRules struct {
+	rules      []robotsRule
+	crawlDelay time.Duration
+	sitemaps   []*nurl.URL
+}
+
+// robotsGroup is a group of rules that applies to several user agents.
+type robotsGroup struct {
+	agents     []string
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+var (
+	allowAllRobots    = &robotsRules{}
+	disallowAllRobots = &robotsRules{rules: []robotsRule{{pattern: "/", allow: false}}}
+)
+
+// parseRobots parses robots.txt from the reader and returns the rules that applied
+// for the specified product token, e.g. "go-trafilatura". Following RFC 9309, the
+// group is selected by matching its user agent with the token case-insensitively,
+// and the group for "*" is only used if there are no matching groups.
+func parseRobots(r io.Reader, productToken string) *robotsRules {
+	var groups []*robotsGroup
+	var current *robotsGroup
+	var sitemaps []*nurl.URL
+	lastIsAgent := false
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		// Remove comment from the line
+		line := scanner.Text()
+		if idx := strings.Index(line, "#"); idx >= 0 {
+			line = line[:idx]
+		}
+
+		// Split key and value
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		key := strings.ToLower(strings.TrimSpace(parts[0]))
+		value := strings.TrimSpace(parts[1])
+
+		switch key {
+		case "user-agent":
+			// Consecutive user-agent lines share the same group
+			if current == nil || !lastIsAgent {
+				current = &robotsGroup{}
+				groups = append(groups, current)
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+			lastIsAgent = true
+			continue
+
+		case "allow", "disallow":
+			// Empty disallow means everything is allowed, so it can be skipped
+			if current != nil && value != "" {
+				current.rules = append(current.rules, robotsRule{
+					pattern: value,
+					allow:   key == "allow",
+				})
+			}
+
+		case "crawl-delay":
+			delay, err := strconv.ParseFloat(value, 64)
+			if current != nil && err == nil && delay > 0 {
+				current.crawlDelay = time.Duration(delay * float64(time.Second))
+			}
+
+		case "sitemap":
+			if parsedURL, valid := validateURL(value); valid {
+				sitemaps = append(sitemaps, parsedURL)
+			}
+		}
+
+		lastIsAgent = false
+	}
+
+	// Find the groups that match our product token, or fallback to the groups for
+	// any agent. If several groups match, they are combined into one.
+	var matchedGroups, anyGroups []*robotsGroup
+	productToken = strings.ToLower(strings.TrimSpace(productToken))
+
+	for _, group := range groups {
+		agents := sliceToMap(group.agents...)
+		if _, matched := agents[productToken]; matched && productToken != "" {
+			matchedGroups = append(matchedGroups, group)
+		} else if _, isAny := agents["*"]; isAny {
+			anyGroups = append(anyGroups, group)
+		}
+	}
+
+	if len(matchedGroups) == 0 {
+		matchedGroups = anyGroups
+	}
+
+	result := &robotsRules{sitemaps: sitemaps}
+	for _, group := range matchedGroups {
+		result.rules = append(result.rules, group.rules...)
+		if group.crawlDelay > result.crawlDelay {
+			result.crawlDelay = group.crawlDelay
+		}
+	}
+
+	return result
+}
+
+// isAllowed checks if the URL is allowed to be crawled. The rule with longest
+// matching pattern is used, and in case of tie the Allow rule wins.
+func (rr *robotsRules) isAllowed(url *nurl.URL) bool {
+	path := url.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+
+	if url.RawQuery != "" {
+		path += "?" + url.RawQuery
+	}
+
+	allowed := true
+	bestLength := -1
+	for _, rule := range rr.rules {
+		if !robotsPatternMatch(rule.pattern, path) {
+			continue
+		}
+
+		patternLength := len(rule.pattern)
+		if patternLength > bestLength || (patternLength == bestLength && rule.allow) {
+			bestLength = patternLength
+			allowed = rule.allow
+		}
+	}
+
+	return allowed
+}
+
+// robotsPatternMatch checks if path matched with robots.txt pattern, which
+// supports `*` as wildcard and `$` as the end of the path.
+func robotsPatternMatch(pattern, path string) bool {
+	mustEnd := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+
+	// The first part must be the prefix of path
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+
+	// The rest of parts must appear in order
+	remaining := path[len(parts[0]):]
+	for i, part := range parts[1:] {
+		// For the last part that must be the end of path, match it from the back
+		if mustEnd && i == len(parts)-2 {
+			return strings.HasSuffix(remaining, part)
+		}
+
+		idx := strings.Index(remaining, part)
+		if idx < 0 {
+			return false
+		}
+		remaining = remaining[idx+len(part):]
+	}
+
+	return !mustEnd || remaining == ""
+}
+
+// robotsChecker downloads robots.txt for each host and caches it.
+type robotsChecker struct {
+	sync.Mutex
+
+	httpClient   *http.Client
+	userAgent    string
+	productToken string
+	semaphore    *semaphore.Weighted
+	cache        map[string]*robotsCacheEntry
+}
+
+type robotsCacheEntry struct {
+	once  sync.Once
+	rules *robotsRules
+}
+
+// newRobotsChecker creates checker that downloads robots.txt using the user agent,
+// then applies its rules for the product token. If semaphore is not nil, it's used
+// to limit the concurrent downloads.
+func newRobotsChecker(httpClient *http.Client, userAgent string, productToken string, sem *semaphore.Weighted) *robotsChecker {
+	if productToken == "" {
+		productToken = defaultRobotsAgent
+	}
+
+	return &robotsChecker{
+		httpClient:   httpClient,
+		userAgent:    userAgent,
+		productToken: productToken,
+		semaphore:    sem,
+		cache:        make(map[string]*robotsCacheEntry),
+	}
+}
+
+// rulesFor returns robots.txt rules for the host of the URL. The robots.txt
+// for each host is only downloaded once.
+func (rc *robotsChecker) rulesFor(url *nurl.URL) *robotsRules {
+	key := url.Scheme + "://" + url.Host
+
+	rc.Lock()
+	entry, exist := rc.cache[key]
+	if !exist {
+		entry = &robotsCacheEntry{}
+		rc.cache[key] = entry
+	}
+	rc.Unlock()
+
+	entry.once.Do(func() {
+		entry.rules = rc.download(key + "/robots.txt")
+	})
+
+	return entry.rules
+}
+
+// isAllowed checks if the URL is allowed by robots.txt of its host.
+func (rc *robotsChecker) isAllowed(url *nurl.URL) bool {
+	return rc.rulesFor(url).isAllowed(url)
+}
+
+// crawlDelay returns the Crawl-delay for the host of the URL.
+func (rc *robotsChecker) crawlDelay(url *nurl.URL) time.Duration {
+	return rc.rulesFor(url).crawlDelay
+}
+
+// sitemaps returns the sitemap URLs that listed in robots.txt of the URL's host.
+func (rc *robotsChecker) sitemaps(url *nurl.URL) []*nurl.URL {
+	return rc.rulesFor(url).sitemaps
+}
+
+func (rc *robotsChecker) download(robotsURL string) *robotsRules {
+	if rc.semaphore != nil {
+		rc.semaphore.Acquire(context.Background(), 1)
+		defer rc.semaphore.Release(1)
+	}
+
+	logrus.Println("downloading robots.txt:", robotsURL)
+	resp, err := download(rc.httpClient, rc.userAgent, robotsURL)
+	if err != nil {
+		logrus.Warnf("failed to download %s, assuming everything is disallowed: %v", robotsURL, err)
+		return disallowAllRobots
+	}
+	defer resp.Body.Close()
+
+	// Following RFC 9309, if robots.txt is unavailable then everything is allowed,
+	// while if server is unreachable then everything is disallowed.
+	switch {
+	case resp.StatusCode >= 500:
+		logrus.Warnf("%s returns status %d, assuming everything is disallowed", robotsURL, resp.StatusCode)
+		return disallowAllRobots
+	case resp.StatusCode >= 400:
+		return allowAllRobots
+	}
+
+	return parseRobots(resp.Body, rc.productToken)
+}
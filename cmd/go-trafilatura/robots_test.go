@@ -0,0 +1,206 @@
+// This file is part of go-trafilatura, Go package for extracting readable
+// content, comments and metadata from a web page. Source available in
+// <https://github.com/markusmobius/go-trafilatura>.
+// Copyright (C) 2021 Markus Mobius
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or (at your
+// option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+// or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License
+// for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	nurl "net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sync/semaphore"
+)
+
+func Test_robotsPatternMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		match   bool
+	}{
+		{"/", "/", true},
+		{"/", "/page", true},
+		{"/fish", "/fish", true},
+		{"/fish", "/fish.html", true},
+		{"/fish", "/fish/salmon.html", true},
+		{"/fish", "/Fish.asp", false},
+		{"/fish", "/catfish", false},
+		{"/fish/", "/fish", false},
+		{"/*.php", "/index.php", true},
+		{"/*.php", "/folder/filename.php?parameters", true},
+		{"/*.php", "/windows.PHP", false},
+		{"/*.php$", "/filename.php", true},
+		{"/*.php$", "/filename.php?parameters", false},
+		{"/*.php$", "/filename.php5", false},
+		{"/fish*.php", "/fish/food.php", true},
+		{"/fish*.php", "/Fish.php", false},
+		{"/a*b*c", "/axxbxxc", true},
+		{"/a*b*c", "/axxcxxb", false},
+		{"*", "/anything", true},
+		{"/end$", "/end", true},
+		{"/end$", "/end/", false},
+		{"/*$", "/", true},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.match, robotsPatternMatch(test.pattern, test.path), "%q on %q", test.pattern, test.path)
+	}
+}
+
+func Test_parseRobots(t *testing.T) {
+	robotsTxt := `
+# Comment line
+User-agent: *
+Disallow: /private/
+Allow: /private/public.html
+Crawl-delay: 2
+
+User-agent: Go-Trafilatura
+User-agent: OtherBot
+Disallow: /no-trafilatura/
+Disallow: /page
+Allow: /page$
+Crawl-delay: 0.5
+
+User-agent: go-trafilatura
+Disallow: /also-no-trafilatura # trailing comment
+
+User-agent: Mozilla
+Disallow: /
+
+User-agent: go
+Disallow: /
+
+Sitemap: https://example.org/sitemap.xml
+Sitemap: not-an-url
+`
+
+	parseURL := func(path string) *nurl.URL {
+		url, _ := nurl.ParseRequestURI("https://example.org" + path)
+		return url
+	}
+
+	tests := []struct {
+		productToken string
+		path         string
+		allowed      bool
+	}{
+		// Group for the product token, combined from several groups
+		{"go-trafilatura", "/", true},
+		{"go-trafilatura", "/private/page.html", true},
+		{"go-trafilatura", "/no-trafilatura/page.html", false},
+		{"go-trafilatura", "/also-no-trafilatura", false},
+		{"GO-TRAFILATURA", "/no-trafilatura/page.html", false},
+		{"otherbot", "/no-trafilatura/page.html", false},
+		{"otherbot", "/also-no-trafilatura", true},
+
+		// Allow wins over disallow with the same length, longest pattern wins otherwise
+		{"go-trafilatura", "/page", true},
+		{"go-trafilatura", "/page.html", false},
+
+		// Group for any agent, since there are no group for the product token
+		{"unknown-bot", "/private/page.html", false},
+		{"unknown-bot", "/private/public.html", true},
+		{"unknown-bot", "/no-trafilatura/page.html", true},
+		{"", "/private/page.html", false},
+
+		// The agent must match the whole token, e.g. "go" doesn't match "go-trafilatura"
+		// and "mozilla" doesn't match the browser user agent
+		{"mozilla", "/public.html", false},
+		{"go", "/public.html", false},
+		{"Mozilla/5.0 (X11; Linux x86_64; rv:88.0) Gecko/20100101 Firefox/88.0", "/public.html", true},
+	}
+
+	for _, test := range tests {
+		rules := parseRobots(strings.NewReader(robotsTxt), test.productToken)
+		assert.Equal(t, test.allowed, rules.isAllowed(parseURL(test.path)), "%s on %s", test.productToken, test.path)
+	}
+
+	// Crawl delay and sitemaps
+	rules := parseRobots(strings.NewReader(robotsTxt), "go-trafilatura")
+	assert.Equal(t, 500*time.Millisecond, rules.crawlDelay)
+	assert.Len(t, rules.sitemaps, 1)
+	assert.Equal(t, "https://example.org/sitemap.xml", rules.sitemaps[0].String())
+
+	rules = parseRobots(strings.NewReader(robotsTxt), "unknown-bot")
+	assert.Equal(t, 2*time.Second, rules.crawlDelay)
+
+	// Query is included when matching the rules, and empty robots.txt allows everything
+	rules = parseRobots(strings.NewReader("User-agent: *\nDisallow: /*?sort="), "go-trafilatura")
+	assert.False(t, rules.isAllowed(parseURL("/list?sort=asc")))
+	assert.True(t, rules.isAllowed(parseURL("/list?page=2")))
+	assert.True(t, parseRobots(strings.NewReader(""), "go-trafilatura").isAllowed(parseURL("/")))
+}
+
+func Test_robotsChecker(t *testing.T) {
+	var mutex sync.Mutex
+	var inFlight, maxInFlight, nDownloads int
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		nDownloads++
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mutex.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte("User-agent: go-trafilatura\nDisallow: /private\n\nSitemap: https://example.org/sitemap.xml\n"))
+
+		mutex.Lock()
+		inFlight--
+		mutex.Unlock()
+	})
+
+	var servers []*httptest.Server
+	for i := 0; i < 3; i++ {
+		server := httptest.NewServer(handler)
+		defer server.Close()
+		servers = append(servers, server)
+	}
+
+	// Rules are chosen by the product token instead of the full user agent, and
+	// robots.txt of each host is only downloaded once under the semaphore
+	checker := newRobotsChecker(http.DefaultClient, defaultUserAgent, "", semaphore.NewWeighted(1))
+
+	var wg sync.WaitGroup
+	for _, server := range servers {
+		for i := 0; i < 3; i++ {
+			url, _ := nurl.Parse(server.URL + "/private/page")
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.False(t, checker.isAllowed(url))
+			}()
+		}
+	}
+	wg.Wait()
+
+	assert.Equal(t, 3, nDownloads)
+	assert.Equal(t, 1, maxInFlight)
+
+	url, _ := nurl.Parse(servers[0].URL + "/public")
+	assert.True(t, checker.isAllowed(url))
+	assert.Len(t, checker.sitemaps(url), 1)
+	assert.Equal(t, 3, nDownloads)
+}
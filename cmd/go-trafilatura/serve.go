@@ -0,0 +1,594 @@
+// This file is part of go-trafilatura, Go package for extracting readable
+// content, comments and metadata from a web page. Source available in
+// <https://github.com/markusmobius/go-trafilatura>.
+// Copyright (C) 2021 Markus Mobius
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or (at your
+// option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+// or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License
+// for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	nurl "net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/markusmobius/go-trafilatura"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"golang.org/x/sync/semaphore"
+)
+
+func serveCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "serve [flags]",
+		Short: "Run HTTP server that exposes extraction as REST API",
+		Long: "Run HTTP server that exposes extraction as REST API. Use \"POST /extract\" to extract\n" +
+			"from posted HTML or JSON body, or \"GET /extract?url=<url>\" to extract from a url.\n" +
+			"Options can be specified in JSON body or query, using the same name as the flags.\n" +
+			"There are also \"GET /health\" and \"GET /metrics\" for monitoring the server.",
+		Args: cobra.NoArgs,
+		Run:  serveCmdHandler,
+	}
+
+	flags := cmd.Flags()
+	flags.String("addr", ":8080", "address to listen on")
+	flags.Int64("max-body-size", 10, "max size of request body and downloaded page in MB (default 10)")
+	flags.Int("max-concurrent", 10, "max number of concurrent extraction (default 10)")
+	flags.Int("request-timeout", 60, "timeout for each request in seconds (default 60)")
+	flags.Bool("allow-private", false, "allow extracting url in loopback, link-local or private network")
+	return cmd
+}
+
+func serveCmdHandler(cmd *cobra.Command, args []string) {
+	flags := cmd.Flags()
+	addr, _ := flags.GetString("addr")
+	maxBodySize, _ := flags.GetInt64("max-body-size")
+	maxConcurrent, _ := flags.GetInt("max-concurrent")
+	requestTimeout, _ := flags.GetInt("request-timeout")
+	userAgent, _ := flags.GetString("user-agent")
+	allowPrivate, _ := flags.GetBool("allow-private")
+
+	defaultRequest, err := defaultExtractRequest(cmd)
+	if err != nil {
+		logrus.Fatalln(err)
+	}
+
+	handler := newExtractServer(serverConfig{
+		MaxBodySize:    maxBodySize * 1024 * 1024,
+		MaxConcurrent:  maxConcurrent,
+		RequestTimeout: time.Duration(requestTimeout) * time.Second,
+		HttpClient:     createHttpClient(cmd),
+		UserAgent:      userAgent,
+		AllowPrivate:   allowPrivate,
+		DefaultRequest: defaultRequest,
+	})
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       handler.config.RequestTimeout,
+		WriteTimeout:      handler.config.RequestTimeout + 10*time.Second,
+	}
+
+	logrus.Printf("listening on %s", addr)
+	if err := server.ListenAndServe(); err != nil {
+		logrus.Fatalf("server stopped: %v", err)
+	}
+}
+
+// serverConfig is the configuration for extraction server.
+type serverConfig struct {
+	// MaxBodySize is the max size in bytes of request body and downloaded page.
+	MaxBodySize int64
+
+	// MaxConcurrent is the max number of extraction that run at the same time.
+	MaxConcurrent int
+
+	// RequestTimeout is the max duration for handling a request, including
+	// waiting for free slot, downloading the page and extracting it.
+	RequestTimeout time.Duration
+
+	// HttpClient and UserAgent are used to download the page.
+	HttpClient *http.Client
+	UserAgent  string
+
+	// AllowPrivate allows downloading the page from loopback, link-local and private
+	// network. It's disabled by default, so the server can't be used to reach the
+	// internal services.
+	AllowPrivate bool
+
+	// DefaultRequest contains the default options that used when request doesn't specify them.
+	DefaultRequest extractRequest
+}
+
+// extractRequest is the request for extraction, parsed from JSON body or query.
+type extractRequest struct {
+	URL               string `json:"url"`
+	HTML              string `json:"html"`
+	Format            string `json:"format"`
+	Language          string `json:"language"`
+	NoFallback        bool   `json:"noFallback"`
+	NoComments        bool   `json:"noComments"`
+	NoTables          bool   `json:"noTables"`
+	Images            bool   `json:"images"`
+	Links             bool   `json:"links"`
+	MediaPlaceholders bool   `json:"mediaPlaceholders"`
+	Deduplicate       bool   `json:"deduplicate"`
+	HasMetadata       bool   `json:"hasMetadata"`
+}
+
+// defaultExtractRequest creates default request using the flags from command. It fails
+// if the format is not supported by server, since otherwise every request that doesn't
+// specify its format would be rejected.
+func defaultExtractRequest(cmd *cobra.Command) (extractRequest, error) {
+	flags := cmd.Flags()
+	opts := createExtractorOptions(cmd)
+	format, _ := flags.GetString("format")
+	if format == "" {
+		format = "json"
+	}
+
+	if !isServerFormat(format) {
+		return extractRequest{}, fmt.Errorf("format %q is not supported by server", format)
+	}
+
+	return extractRequest{
+		Format:            format,
+		Language:          opts.TargetLanguage,
+		NoFallback:        opts.NoFallback,
+		NoComments:        opts.ExcludeComments,
+		NoTables:          opts.ExcludeTables,
+		Images:            opts.IncludeImages,
+		Links:             opts.IncludeLinks,
+		MediaPlaceholders: opts.MediaPlaceholders,
+		Deduplicate:       opts.Deduplicate,
+		HasMetadata:       opts.HasEssentialMetadata,
+	}, nil
+}
+
+// isServerFormat checks if the output format is supported by server.
+func isServerFormat(format string) bool {
+	switch format {
+	case "json", "html", "md", "markdown", "txt":
+		return true
+	default:
+		return false
+	}
+}
+
+// applyQuery overrides the request options using URL query, which uses the same name as CLI flags.
+func (er *extractRequest) applyQuery(query nurl.Values) error {
+	strFields := map[string]*string{
+		"url":      &er.URL,
+		"format":   &er.Format,
+		"language": &er.Language,
+	}
+
+	boolFields := map[string]*bool{
+		"no-fallback":        &er.NoFallback,
+		"no-comments":        &er.NoComments,
+		"no-tables":          &er.NoTables,
+		"images":             &er.Images,
+		"links":              &er.Links,
+		"media-placeholders": &er.MediaPlaceholders,
+		"deduplicate":        &er.Deduplicate,
+		"has-metadata":       &er.HasMetadata,
+	}
+
+	for name, field := range strFields {
+		if _, exist := query[name]; exist {
+			*field = query.Get(name)
+		}
+	}
+
+	for name, field := range boolFields {
+		if _, exist := query[name]; !exist {
+			continue
+		}
+
+		// Empty value is treated as true, e.g. "?no-fallback"
+		value := query.Get(name)
+		if value == "" {
+			*field = true
+			continue
+		}
+
+		boolValue, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid value for %s: %q", name, value)
+		}
+		*field = boolValue
+	}
+
+	return nil
+}
+
+// options converts the request into extractor options.
+func (er *extractRequest) options() trafilatura.Options {
+	return trafilatura.Options{
+		TargetLanguage:       er.Language,
+		NoFallback:           er.NoFallback,
+		ExcludeComments:      er.NoComments,
+		ExcludeTables:        er.NoTables,
+		IncludeImages:        er.Images,
+		IncludeLinks:         er.Links,
+		MediaPlaceholders:    er.MediaPlaceholders,
+		Deduplicate:          er.Deduplicate,
+		HasEssentialMetadata: er.HasMetadata,
+	}
+}
+
+// extractServer is HTTP handler that exposes extraction as REST API.
+type extractServer struct {
+	config    serverConfig
+	semaphore *semaphore.Weighted
+	handler   http.Handler
+	metrics   *serverMetrics
+	extractor *trafilatura.PrometheusMetrics
+}
+
+func newExtractServer(cfg serverConfig) *extractServer {
+	if cfg.MaxConcurrent <= 0 {
+		cfg.MaxConcurrent = 1
+	}
+
+	if cfg.HttpClient == nil {
+		cfg.HttpClient = &http.Client{Timeout: cfg.RequestTimeout}
+	}
+
+	if !cfg.AllowPrivate {
+		cfg.HttpClient = publicHttpClient(cfg.HttpClient)
+	}
+
+	if cfg.UserAgent == "" {
+		cfg.UserAgent = defaultUserAgent
+	}
+
+	if cfg.DefaultRequest.Format == "" {
+		cfg.DefaultRequest.Format = "json"
+	}
+
+	s := &extractServer{
+		config:    cfg,
+		semaphore: semaphore.NewWeighted(int64(cfg.MaxConcurrent)),
+		metrics:   newServerMetrics(),
+		extractor: trafilatura.NewPrometheusMetrics(),
+	}
+
+	var extractHandler http.Handler = http.HandlerFunc(s.handleExtract)
+	if cfg.RequestTimeout > 0 {
+		timeoutMessage := `{"error":"request timeout"}`
+		extractHandler = http.TimeoutHandler(extractHandler, cfg.RequestTimeout, timeoutMessage)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/extract", s.metrics.instrument("/extract", extractHandler))
+	mux.Handle("/health", s.metrics.instrument("/health", http.HandlerFunc(s.handleHealth)))
+	mux.Handle("/metrics", http.HandlerFunc(s.handleMetrics))
+	s.handler = mux
+
+	return s
+}
+
+func (s *extractServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.handler.ServeHTTP(w, r)
+}
+
+func (s *extractServer) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeServerJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (s *extractServer) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	s.metrics.writePrometheus(w)
+	s.extractor.WritePrometheus(w)
+}
+
+func (s *extractServer) handleExtract(w http.ResponseWriter, r *http.Request) {
+	// Parse request
+	req, status, err := s.parseRequest(r)
+	if err != nil {
+		writeServerError(w, status, err)
+		return
+	}
+
+	if req.HTML == "" && req.URL == "" {
+		writeServerError(w, http.StatusBadRequest, fmt.Errorf("either html or url must be specified"))
+		return
+	}
+
+	var originalURL *nurl.URL
+	if req.URL != "" {
+		var valid bool
+		if originalURL, valid = validateURL(req.URL); !valid {
+			writeServerError(w, http.StatusBadRequest, fmt.Errorf("url is not valid: %q", req.URL))
+			return
+		}
+	}
+
+	// Wait for free slot
+	if err := s.semaphore.Acquire(r.Context(), 1); err != nil {
+		writeServerError(w, http.StatusServiceUnavailable, fmt.Errorf("server is busy"))
+		return
+	}
+	defer s.semaphore.Release(1)
+
+	atomic.AddInt64(&s.metrics.inFlight, 1)
+	defer atomic.AddInt64(&s.metrics.inFlight, -1)
+
+	// If HTML is not specified, download it
+	page := &fetchedPage{
+		URL:    originalURL,
+		Header: http.Header{},
+		Body:   []byte(req.HTML),
+	}
+
+	if req.HTML == "" {
+		page, err = fetchPage(s.config.HttpClient, s.config.UserAgent, originalURL, nil, s.config.MaxBodySize)
+		switch {
+		case errors.Is(err, errPageTooLarge):
+			writeServerError(w, http.StatusRequestEntityTooLarge, err)
+			return
+		case errors.Is(err, errPrivateAddress):
+			writeServerError(w, http.StatusForbidden, err)
+			return
+		case err != nil:
+			writeServerError(w, http.StatusBadGateway, fmt.Errorf("failed to download page: %w", err))
+			return
+		}
+	}
+
+	// Extract the page
+	opts := req.options()
+	opts.Metrics = s.extractor
+	opts.OriginalURL = page.URL
+	opts.ContentType = page.Header.Get("Content-Type")
+	opts.MaxDuration = s.config.RequestTimeout
+	result, err := trafilatura.Extract(bytes.NewReader(page.Body), opts)
+	if err != nil {
+		writeServerError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	// Write the result using the requested format
+	buffer := bytes.NewBuffer(nil)
+	switch req.Format {
+	case "json":
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		err = writeJSON(buffer, result)
+	case "html":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		err = writeHTML(buffer, result, false)
+	case "md", "markdown":
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		err = writeMarkdown(buffer, result, false)
+	default:
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		err = writeText(buffer, result, 0)
+	}
+
+	if err != nil {
+		w.Header().Del("Content-Type")
+		writeServerError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(buffer.Bytes())
+}
+
+// parseRequest parses the extraction request from the query and body. If it failed,
+// it also returns the status code that should be sent to client.
+func (s *extractServer) parseRequest(r *http.Request) (extractRequest, int, error) {
+	req := s.config.DefaultRequest
+	if err := req.applyQuery(r.URL.Query()); err != nil {
+		return req, http.StatusBadRequest, err
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		req.HTML = ""
+	case http.MethodPost:
+		// Read body with size limit
+		body := r.Body
+		if s.config.MaxBodySize > 0 {
+			body = http.MaxBytesReader(nil, r.Body, s.config.MaxBodySize)
+		}
+
+		content, err := io.ReadAll(body)
+		if err != nil {
+			if strings.Contains(err.Error(), "request body too large") {
+				return req, http.StatusRequestEntityTooLarge, fmt.Errorf("request body is too large")
+			}
+			return req, http.StatusBadRequest, err
+		}
+
+		// JSON body may contain options, while other body is treated as HTML
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if mediaType == "application/json" {
+			req.HTML = ""
+			if err := json.Unmarshal(content, &req); err != nil {
+				return req, http.StatusBadRequest, fmt.Errorf("invalid json: %w", err)
+			}
+		} else {
+			req.HTML = string(content)
+		}
+	default:
+		return req, http.StatusMethodNotAllowed, fmt.Errorf("method %s is not allowed", r.Method)
+	}
+
+	if !isServerFormat(req.Format) {
+		return req, http.StatusBadRequest, fmt.Errorf("unknown format: %q", req.Format)
+	}
+
+	return req, 0, nil
+}
+
+func writeServerJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+func writeServerError(w http.ResponseWriter, status int, err error) {
+	writeServerJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// serverMetrics collects the statistic of HTTP requests in extraction server. The
+// statistic of the extraction itself is collected by the extractor's metrics hook.
+type serverMetrics struct {
+	sync.Mutex
+
+	inFlight int64
+	requests map[[2]string]int64
+}
+
+func newServerMetrics() *serverMetrics {
+	return &serverMetrics{
+		requests: make(map[[2]string]int64),
+	}
+}
+
+// instrument wraps the handler to count the requests by their path and status code.
+func (sm *serverMetrics) instrument(path string, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		handler.ServeHTTP(sw, r)
+
+		sm.Lock()
+		sm.requests[[2]string{path, strconv.Itoa(sw.status)}]++
+		sm.Unlock()
+	})
+}
+
+// writePrometheus writes the metrics in Prometheus text format.
+func (sm *serverMetrics) writePrometheus(w io.Writer) {
+	sm.Lock()
+	defer sm.Unlock()
+
+	var keys [][2]string
+	for key := range sm.requests {
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+
+	fmt.Fprintln(w, "# HELP trafilatura_server_requests_total Number of HTTP requests by path and status code.")
+	fmt.Fprintln(w, "# TYPE trafilatura_server_requests_total counter")
+	for _, key := range keys {
+		fmt.Fprintf(w, "trafilatura_server_requests_total{path=%q,code=%q} %d\n", key[0], key[1], sm.requests[key])
+	}
+
+	fmt.Fprintln(w, "# HELP trafilatura_server_extractions_in_flight Number of extractions that currently running.")
+	fmt.Fprintln(w, "# TYPE trafilatura_server_extractions_in_flight gauge")
+	fmt.Fprintf(w, "trafilatura_server_extractions_in_flight %d\n", atomic.LoadInt64(&sm.inFlight))
+}
+
+// statusRecorder is response writer that records the status code.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sr *statusRecorder) WriteHeader(status int) {
+	sr.status = status
+	sr.ResponseWriter.WriteHeader(status)
+}
+
+// errPrivateAddress is returned when the server is asked to download from private network.
+var errPrivateAddress = errors.New("address is not public")
+
+// privateNetworks is the loopback, link-local, private and other special-purpose networks
+// that not allowed to be downloaded by server.
+var privateNetworks = parseNetworks(
+	"0.0.0.0/8", "10.0.0.0/8", "100.64.0.0/10", "127.0.0.0/8", "169.254.0.0/16",
+	"172.16.0.0/12", "192.0.0.0/24", "192.168.0.0/16", "198.18.0.0/15", "224.0.0.0/4",
+	"240.0.0.0/4", "::/128", "::1/128", "64:ff9b::/96", "fc00::/7", "fe80::/10", "ff00::/8",
+)
+
+// publicHttpClient returns copy of the client that refuses to connect to private network.
+// The address is checked after it's resolved, so it also covers the redirects and
+// the host names that resolved to private address.
+func publicHttpClient(client *http.Client) *http.Client {
+	transport, ok := client.Transport.(*http.Transport)
+	if !ok || transport == nil {
+		transport = http.DefaultTransport.(*http.Transport)
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   checkPublicAddress,
+	}
+
+	transport = transport.Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	clone := *client
+	clone.Transport = transport
+	return &clone
+}
+
+// checkPublicAddress makes sure the dialed address is not in private network.
+func checkPublicAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("%w: %s", errPrivateAddress, host)
+	}
+
+	for _, private := range privateNetworks {
+		if private.Contains(ip) {
+			return fmt.Errorf("%w: %s", errPrivateAddress, host)
+		}
+	}
+
+	return nil
+}
+
+func parseNetworks(cidrs ...string) []*net.IPNet {
+	var networks []*net.IPNet
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
@@ -0,0 +1,149 @@
+// This file is part of go-trafilatura, Go package for extracting readable
+// content, comments and metadata from a web page. Source available in
+// <https://github.com/markusmobius/go-trafilatura>.
+// Copyright (C) 2021 Markus Mobius
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or (at your
+// option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+// or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License
+// for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ExtractServer(t *testing.T) {
+	rawHTML, err := os.ReadFile("../../test-files/mock/adac.de.kindersitze.html")
+	assert.Nil(t, err)
+
+	// Prepare server for the web page that will be extracted
+	pageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if r.URL.Path == "/large" {
+			w.Write([]byte(strings.Repeat("a", 2*1024*1024)))
+			return
+		}
+		w.Write(rawHTML)
+	}))
+	defer pageServer.Close()
+
+	// Prepare extraction server
+	server := httptest.NewServer(newExtractServer(serverConfig{
+		MaxBodySize:   1024 * 1024,
+		MaxConcurrent: 2,
+		AllowPrivate:  true,
+	}))
+	defer server.Close()
+
+	request := func(method, path, contentType, body string) (int, string) {
+		req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		assert.Nil(t, err)
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		assert.Nil(t, err)
+		defer resp.Body.Close()
+
+		content, err := io.ReadAll(resp.Body)
+		assert.Nil(t, err)
+		return resp.StatusCode, string(content)
+	}
+
+	// Health check
+	status, body := request("GET", "/health", "", "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, `"ok"`)
+
+	// Posted HTML, returns JSON by default
+	status, body = request("POST", "/extract?no-fallback", "text/html", string(rawHTML))
+	assert.Equal(t, http.StatusOK, status)
+
+	var result map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(body), &result))
+	assert.Contains(t, result["contentText"], "Kindersitze")
+
+	// JSON body with options
+	jsonBody, _ := json.Marshal(map[string]interface{}{
+		"html":       string(rawHTML),
+		"format":     "md",
+		"noFallback": true,
+	})
+	status, body = request("POST", "/extract", "application/json", string(jsonBody))
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, "# 18 Kindersitze im Test")
+
+	// Extract from URL
+	status, body = request("GET", "/extract?format=txt&url="+pageServer.URL, "", "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, "Kindersitze")
+
+	// Invalid requests
+	status, _ = request("GET", "/extract", "", "")
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	status, _ = request("GET", "/extract?format=pdf&url="+pageServer.URL, "", "")
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	status, _ = request("POST", "/extract", "text/html", strings.Repeat("a", 2*1024*1024))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, status)
+
+	status, _ = request("GET", "/extract?url="+pageServer.URL+"/large", "", "")
+	assert.Equal(t, http.StatusRequestEntityTooLarge, status)
+
+	// Metrics
+	status, body = request("GET", "/metrics", "", "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, `trafilatura_server_requests_total{path="/extract",code="200"} 3`)
+	assert.Contains(t, body, `trafilatura_extractions_total{outcome="success"} 3`)
+
+	// By default, server refuses to download from private network
+	publicServer := httptest.NewServer(newExtractServer(serverConfig{MaxConcurrent: 1}))
+	defer publicServer.Close()
+
+	for _, url := range []string{pageServer.URL, "http://localhost:1/", "http://[::1]:1/", "http://10.0.0.1:1/",
+		"http://169.254.169.254/", "http://[64:ff9b::a00:1]:1/"} {
+		resp, err := http.Get(publicServer.URL + "/extract?url=" + url)
+		assert.Nil(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode, url)
+	}
+}
+
+func Test_defaultExtractRequest(t *testing.T) {
+	cmd := serveCmd()
+	cmd.Flags().String("format", "", "")
+
+	req, err := defaultExtractRequest(cmd)
+	assert.Nil(t, err)
+	assert.Equal(t, "json", req.Format)
+
+	assert.Nil(t, cmd.Flags().Set("format", "md"))
+	req, err = defaultExtractRequest(cmd)
+	assert.Nil(t, err)
+	assert.Equal(t, "md", req.Format)
+
+	// Format that not supported by server is rejected on start
+	assert.Nil(t, cmd.Flags().Set("format", "chunks"))
+	_, err = defaultExtractRequest(cmd)
+	assert.NotNil(t, err)
+}
@@ -19,10 +19,8 @@
 package main
 
 import (
-	"bufio"
 	"context"
 	"fmt"
-	"net/http"
 	nurl "net/url"
 	"os"
 	fp "path/filepath"
@@ -51,22 +49,20 @@ func sitemapCmd() *cobra.Command {
 
 	flags := cmd.Flags()
 	flags.StringP("output", "o", ".", "output directory for the result (default current work dir)")
-	flags.Int("parallel", 10, "number of concurrent download at a time (default 10)")
-	flags.Int("delay", 0, "delay between each url download in seconds (default 0)")
 	flags.String("filter", "", "regular expression for allowed url")
 	flags.String("exclude", "", "regular expression for excluded url")
 	flags.StringArray("domains", nil, "list of allowed domains")
 	flags.StringArray("no-domains", nil, "list of excluded domains")
 	flags.Bool("url-only", false, "only print page urls without downloading or processing them")
 
+	addDownloaderFlags(cmd)
 	return cmd
 }
 
 type sitemapCmdHandler struct {
-	userAgent         string
-	httpClient        *http.Client
 	sitemapDownloader *sitemapDownloader
 	pagesDownloader   *batchDownloader
+	robotsChecker     *robotsChecker
 	urlOnly           bool
 }
 
@@ -128,32 +124,33 @@ func newSitemapCmdHandler(cmd *cobra.Command) *sitemapCmdHandler {
 
 	// Prepare pages downloader
 	nameExt := outputExt(cmd)
-	fnWrite := func(result *trafilatura.ExtractResult, url *nurl.URL, idx int) error {
+	fnWrite := func(result *trafilatura.ExtractResult, url *nurl.URL, idx int) (string, error) {
 		name := nameFromURL(url)
 		timestamp := time.Now().Format("150405")
 		id, err := gonanoid.New(6)
 		if err != nil {
-			return err
+			return "", err
 		}
 
 		name = timestamp + "-" + name + "-" + id + nameExt
-		dst, err := os.Create(fp.Join(outputDir, name))
+		dstPath := fp.Join(outputDir, name)
+		dst, err := os.Create(dstPath)
 		if err != nil {
-			return err
+			return "", err
 		}
 		defer dst.Close()
 
-		return writeOutput(dst, result, cmd)
+		return dstPath, writeOutput(dst, result, cmd)
 	}
 
-	pagesDownloader := &batchDownloader{
-		userAgent:      userAgent,
-		httpClient:     httpClient,
-		extractOptions: createExtractorOptions(cmd),
-		semaphore:      semaphore.NewWeighted(int64(nThread)),
-		delay:          time.Duration(delay) * time.Second,
-		cancelOnError:  false,
-		writeFunc:      fnWrite,
+	pagesDownloader := newBatchDownloader(cmd, httpClient, fnWrite)
+
+	// Sitemaps are looked up in robots.txt, so reuse the checker of pages downloader
+	// if it exists, to make sure robots.txt is only downloaded once
+	robots := pagesDownloader.robotsChecker
+	if robots == nil {
+		robotsAgent, _ := flags.GetString("robots-agent")
+		robots = newRobotsChecker(httpClient, userAgent, robotsAgent, pagesDownloader.semaphore)
 	}
 
 	// Make sure output dir exist
@@ -161,10 +158,9 @@ func newSitemapCmdHandler(cmd *cobra.Command) *sitemapCmdHandler {
 
 	// Return handler
 	return &sitemapCmdHandler{
-		userAgent:         userAgent,
-		httpClient:        httpClient,
 		sitemapDownloader: sDownloader,
 		pagesDownloader:   pagesDownloader,
+		robotsChecker:     robots,
 		urlOnly:           urlOnly,
 	}
 }
@@ -186,6 +182,7 @@ func (sch *sitemapCmdHandler) run(args []string) {
 		for _, url := range pageURLs {
 			fmt.Println(url.String())
 		}
+		sch.pagesDownloader.close()
 		return
 	}
 
@@ -212,15 +209,10 @@ func (sch *sitemapCmdHandler) findSitemapURLs(baseURL string) ([]*nurl.URL, erro
 	}
 
 	// If not found, try to check in robots.txt.
-	// Here we'll ignore error since it's possible that a site doesn't have robots.txt
 	parsedURL.Path = "/robots.txt"
-	parsedURL.RawQuery = nurl.Values{}.Encode()
+	parsedURL.RawQuery = ""
 	parsedURL.Fragment = ""
-
-	sitemapURLs, err := sch.findSitemapURLsInRobots(parsedURL.String())
-	if err != nil {
-		logrus.Warnln("failed to look in robots.txt:", err)
-	}
+	sitemapURLs := sch.robotsChecker.sitemaps(parsedURL)
 
 	// If there are no sitemap found, just add the default path.
 	if len(sitemapURLs) == 0 {
@@ -230,46 +222,3 @@ func (sch *sitemapCmdHandler) findSitemapURLs(baseURL string) ([]*nurl.URL, erro
 
 	return sitemapURLs, nil
 }
-
-func (sch *sitemapCmdHandler) findSitemapURLsInRobots(robotsURL string) ([]*nurl.URL, error) {
-	// Download URL
-	logrus.Println("downloading robots.txt:", robotsURL)
-	resp, err := download(sch.httpClient, sch.userAgent, robotsURL)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	// Make sure it's text file
-	contentType := resp.Header.Get("Content-Type")
-	if !strings.Contains(contentType, "text/plain") {
-		return nil, fmt.Errorf("%s is not plain text", robotsURL)
-	}
-
-	// Scan and find sitemap
-	sitemapURLs := []*nurl.URL{}
-	scanner := bufio.NewScanner(resp.Body)
-
-	for scanner.Scan() {
-		line := scanner.Text()
-		line = strings.TrimSpace(line)
-		line = strings.ToLower(line)
-
-		if strings.HasPrefix(line, "sitemap:") {
-			line = strings.TrimPrefix(line, "sitemap:")
-			line = strings.TrimSpace(line)
-
-			parsedURL, valid := validateURL(line)
-			if valid {
-				sitemapURLs = append(sitemapURLs, parsedURL)
-			}
-		}
-	}
-
-	// Return
-	if len(sitemapURLs) == 0 {
-		return nil, fmt.Errorf("sitemap url not found")
-	}
-
-	return sitemapURLs, nil
-}
@@ -0,0 +1,332 @@
+// This file is part of go-trafilatura, Go package for extracting readable
+// content, comments and metadata from a web page. Source available in
+// <https://github.com/markusmobius/go-trafilatura>.
+// Copyright (C) 2021 Markus Mobius
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or (at your
+// option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+// or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License
+// for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/textproto"
+	nurl "net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const warcVersion = "WARC/1.1"
+
+// maxWarcBlockSize is the max size of HTTP response that read from WARC file, both
+// for the content block and for its decompressed body.
+const maxWarcBlockSize = 256 * 1024 * 1024
+
+// errWarcRecordTooLarge is returned when the record is larger than maxWarcBlockSize.
+var errWarcRecordTooLarge = errors.New("warc record is too large")
+
+// warcRecord is a single record in WARC file. The content block is only read for
+// HTTP response, for the other records it's empty.
+type warcRecord struct {
+	Header textproto.MIMEHeader
+	Block  []byte
+}
+
+// Type returns the type of the record, e.g. "response" or "request".
+func (rec *warcRecord) Type() string {
+	return rec.Header.Get("WARC-Type")
+}
+
+// TargetURI returns the original URI of the record.
+func (rec *warcRecord) TargetURI() string {
+	// In WARC 1.0 the URI might be wrapped in angle brackets
+	uri := rec.Header.Get("WARC-Target-URI")
+	uri = strings.TrimPrefix(uri, "<")
+	uri = strings.TrimSuffix(uri, ">")
+	return uri
+}
+
+// isHTTPResponse checks if the record contains a full HTTP response.
+func (rec *warcRecord) isHTTPResponse() bool {
+	contentType := strings.ToLower(rec.Header.Get("Content-Type"))
+	return rec.Type() == "response" && strings.HasPrefix(contentType, "application/http")
+}
+
+// page parses the HTTP response within the record into a fetched page.
+func (rec *warcRecord) page() (*fetchedPage, error) {
+	url, err := nurl.ParseRequestURI(rec.TargetURI())
+	if err != nil {
+		return nil, fmt.Errorf("invalid target uri: %w", err)
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(rec.Block)), nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid http response: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// WARC stores the response as it's sent by server, so it might be compressed
+	var body io.Reader = resp.Body
+	if strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		gzReader, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip body: %w", err)
+		}
+		defer gzReader.Close()
+		body = gzReader
+	}
+
+	page := &fetchedPage{
+		URL:        url,
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+	}
+
+	page.Body, err = io.ReadAll(io.LimitReader(body, maxWarcBlockSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(page.Body) > maxWarcBlockSize {
+		return nil, errWarcRecordTooLarge
+	}
+
+	return page, nil
+}
+
+// warcReader reads records from WARC file, which might be compressed with gzip.
+type warcReader struct {
+	reader *bufio.Reader
+}
+
+func newWarcReader(r io.Reader) (*warcReader, error) {
+	reader := bufio.NewReader(r)
+
+	// Check gzip magic number. Each record in WARC.gz is compressed as separate gzip
+	// member, which is handled by gzip reader since it's in multistream mode by default.
+	magic, _ := reader.Peek(2)
+	if len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gzReader, err := gzip.NewReader(reader)
+		if err != nil {
+			return nil, err
+		}
+		reader = bufio.NewReader(gzReader)
+	}
+
+	return &warcReader{reader: reader}, nil
+}
+
+// next returns the next record in WARC file. The response record that larger than
+// maxWarcBlockSize is rejected, since the size can't be trusted. Once there are no record left, it returns io.EOF.
+func (wr *warcReader) next() (*warcRecord, error) {
+	// Find version line, skipping the empty lines that separate records
+	var line string
+	for {
+		var err error
+		line, err = wr.reader.ReadString('\n')
+		if err == io.EOF && strings.TrimSpace(line) == "" {
+			return nil, io.EOF
+		} else if err != nil && err != io.EOF {
+			return nil, err
+		}
+
+		line = strings.TrimSpace(line)
+		if line != "" {
+			break
+		}
+	}
+
+	if !strings.HasPrefix(line, "WARC/") {
+		return nil, fmt.Errorf("invalid warc version line: %q", line)
+	}
+
+	// Parse header
+	header, err := textproto.NewReader(wr.reader).ReadMIMEHeader()
+	if err != nil {
+		return nil, fmt.Errorf("invalid warc header: %w", err)
+	}
+
+	contentLength, err := strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+	if err != nil || contentLength < 0 {
+		return nil, fmt.Errorf("invalid warc content length: %q", header.Get("Content-Length"))
+	}
+
+	// Skip content block of the records that won't be used, so it's not loaded into memory
+	record := &warcRecord{Header: header}
+	if !record.isHTTPResponse() {
+		if _, err = io.CopyN(io.Discard, wr.reader, contentLength); err != nil {
+			return nil, fmt.Errorf("failed to read warc block: %w", err)
+		}
+		return record, nil
+	}
+
+	// Read content block
+	if contentLength > maxWarcBlockSize {
+		return nil, fmt.Errorf("%w: %d bytes in %s", errWarcRecordTooLarge, contentLength, record.TargetURI())
+	}
+
+	record.Block = make([]byte, contentLength)
+	if _, err = io.ReadFull(wr.reader, record.Block); err != nil {
+		return nil, fmt.Errorf("failed to read warc block: %w", err)
+	}
+
+	return record, nil
+}
+
+// warcWriter writes downloaded pages into WARC file. If file name ends with ".gz",
+// each record will be compressed as separate gzip member.
+type warcWriter struct {
+	sync.Mutex
+
+	file     *os.File
+	compress bool
+}
+
+func newWarcWriter(path string) (*warcWriter, error) {
+	// Append to the existing file, so pages from the previous run of a resumed
+	// crawl are kept in archive.
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	ww := &warcWriter{
+		file:     f,
+		compress: strings.HasSuffix(strings.ToLower(path), ".gz"),
+	}
+
+	// If the archive already exists, it has been started by the previous run
+	if stat.Size() > 0 {
+		return ww, nil
+	}
+
+	// Put info about the software that creates this file
+	info := "software: go-trafilatura\r\nformat: WARC File Format 1.1\r\n"
+	err = ww.writeRecord([][2]string{
+		{"WARC-Type", "warcinfo"},
+		{"Content-Type", "application/warc-fields"},
+	}, []byte(info))
+
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return ww, nil
+}
+
+// writeResponse saves the downloaded page as response record.
+func (ww *warcWriter) writeResponse(page *fetchedPage) error {
+	// Recreate the HTTP response. Since the body has been read by HTTP client,
+	// the length and encoding of the transfer are adjusted to match it.
+	block := bytes.NewBuffer(nil)
+	fmt.Fprintf(block, "HTTP/1.1 %d %s\r\n", page.StatusCode, http.StatusText(page.StatusCode))
+	page.Header.WriteSubset(block, map[string]bool{
+		"Content-Length":    true,
+		"Transfer-Encoding": true,
+	})
+	fmt.Fprintf(block, "Content-Length: %d\r\n\r\n", len(page.Body))
+	block.Write(page.Body)
+
+	return ww.writeRecord([][2]string{
+		{"WARC-Type", "response"},
+		{"WARC-Target-URI", page.URL.String()},
+		{"WARC-Payload-Digest", warcDigest(page.Body)},
+		{"Content-Type", "application/http; msgtype=response"},
+	}, block.Bytes())
+}
+
+// writeRecord writes a record with the specified header fields and content block. The
+// record ID, date, block digest and content length are generated automatically.
+func (ww *warcWriter) writeRecord(fields [][2]string, block []byte) error {
+	recordID, err := newUUID()
+	if err != nil {
+		return err
+	}
+
+	fields = append([][2]string{
+		{"WARC-Record-ID", "<urn:uuid:" + recordID + ">"},
+		{"WARC-Date", time.Now().UTC().Format(time.RFC3339)},
+	}, fields...)
+
+	fields = append(fields,
+		[2]string{"WARC-Block-Digest", warcDigest(block)},
+		[2]string{"Content-Length", strconv.Itoa(len(block))})
+
+	buffer := bytes.NewBuffer(nil)
+	buffer.WriteString(warcVersion + "\r\n")
+	for _, field := range fields {
+		buffer.WriteString(field[0] + ": " + field[1] + "\r\n")
+	}
+	buffer.WriteString("\r\n")
+	buffer.Write(block)
+	buffer.WriteString("\r\n\r\n")
+
+	ww.Lock()
+	defer ww.Unlock()
+
+	if !ww.compress {
+		_, err = ww.file.Write(buffer.Bytes())
+		return err
+	}
+
+	gzWriter := gzip.NewWriter(ww.file)
+	if _, err = gzWriter.Write(buffer.Bytes()); err != nil {
+		return err
+	}
+
+	return gzWriter.Close()
+}
+
+// Close closes the WARC file.
+func (ww *warcWriter) Close() error {
+	ww.Lock()
+	defer ww.Unlock()
+	return ww.file.Close()
+}
+
+// warcDigest returns SHA-1 digest of the content in format that used by WARC.
+func warcDigest(content []byte) string {
+	hash := sha1.Sum(content)
+	return "sha1:" + base32.StdEncoding.EncodeToString(hash[:])
+}
+
+// newUUID generates random UUID (version 4).
+func newUUID() (string, error) {
+	var uuid [16]byte
+	if _, err := rand.Read(uuid[:]); err != nil {
+		return "", err
+	}
+
+	uuid[6] = (uuid[6] & 0x0f) | 0x40
+	uuid[8] = (uuid[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:]), nil
+}
@@ -0,0 +1,160 @@
+// This file is part of go-trafilatura, Go package for extracting readable
+// content, comments and metadata from a web page. Source available in
+// <https://github.com/markusmobius/go-trafilatura>.
+// Copyright (C) 2021 Markus Mobius
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or (at your
+// option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+// or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License
+// for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"io"
+	"net/http"
+	nurl "net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_WarcRoundTrip(t *testing.T) {
+	newPage := func(rawURL string, statusCode int, contentType string, body string) *fetchedPage {
+		url, err := nurl.ParseRequestURI(rawURL)
+		assert.Nil(t, err)
+
+		header := http.Header{}
+		header.Set("Content-Type", contentType)
+		header.Set("Content-Length", "999")
+		header.Set("Transfer-Encoding", "chunked")
+
+		return &fetchedPage{
+			URL:        url,
+			StatusCode: statusCode,
+			Header:     header,
+			Body:       []byte(body),
+		}
+	}
+
+	firstRun := []*fetchedPage{
+		newPage("https://example.org/", 200, "text/html; charset=utf-8", "<html><body><p>Hello</p></body></html>"),
+		newPage("https://example.org/missing", 404, "text/html", "<html><body>Not found</body></html>"),
+	}
+
+	secondRun := []*fetchedPage{
+		newPage("https://example.org/file.pdf", 200, "application/pdf", "%PDF-1.4\r\n\r\nbinary\x00data"),
+	}
+
+	for _, name := range []string{"crawl.warc", "crawl.warc.gz"} {
+		path := filepath.Join(t.TempDir(), name)
+
+		// Write pages in two runs, the second one must append to the first
+		for _, pages := range [][]*fetchedPage{firstRun, secondRun} {
+			ww, err := newWarcWriter(path)
+			assert.Nil(t, err)
+
+			for _, page := range pages {
+				assert.Nil(t, ww.writeResponse(page))
+			}
+			assert.Nil(t, ww.Close())
+		}
+
+		// Read back the records
+		f, err := os.Open(path)
+		assert.Nil(t, err)
+
+		reader, err := newWarcReader(f)
+		assert.Nil(t, err)
+
+		var records []*warcRecord
+		for {
+			record, err := reader.next()
+			if err == io.EOF {
+				break
+			}
+
+			assert.Nil(t, err, name)
+			if err != nil {
+				break
+			}
+			records = append(records, record)
+		}
+		f.Close()
+
+		// Only the first run writes warcinfo
+		expected := append(append([]*fetchedPage{}, firstRun...), secondRun...)
+		if !assert.Len(t, records, len(expected)+1, name) {
+			continue
+		}
+		assert.Equal(t, "warcinfo", records[0].Type(), name)
+
+		for i, page := range expected {
+			record := records[i+1]
+			assert.True(t, record.isHTTPResponse(), name)
+			assert.Equal(t, page.URL.String(), record.TargetURI(), name)
+			assert.Equal(t, warcDigest(page.Body), record.Header.Get("WARC-Payload-Digest"), name)
+			assert.Equal(t, warcDigest(record.Block), record.Header.Get("WARC-Block-Digest"), name)
+
+			readPage, err := record.page()
+			assert.Nil(t, err, name)
+			assert.Equal(t, page.URL.String(), readPage.URL.String(), name)
+			assert.Equal(t, page.StatusCode, readPage.StatusCode, name)
+			assert.Equal(t, page.Header.Get("Content-Type"), readPage.Header.Get("Content-Type"), name)
+			assert.Equal(t, string(page.Body), string(readPage.Body), name)
+		}
+	}
+}
+
+func Test_WarcReaderMalformed(t *testing.T) {
+	readAll := func(content string) ([]*warcRecord, error) {
+		reader, err := newWarcReader(strings.NewReader(content))
+		assert.Nil(t, err)
+
+		var records []*warcRecord
+		for {
+			record, err := reader.next()
+			if err == io.EOF {
+				return records, nil
+			} else if err != nil {
+				return records, err
+			}
+			records = append(records, record)
+		}
+	}
+
+	// Record that won't be used is skipped without reading its block
+	resource := strings.Repeat("x", 1000)
+	records, err := readAll("WARC/1.1\r\nWARC-Type: resource\r\nContent-Length: 1000\r\n\r\n" + resource + "\r\n\r\n")
+	assert.Nil(t, err)
+	assert.Len(t, records, 1)
+	assert.Equal(t, "resource", records[0].Type())
+	assert.Nil(t, records[0].Block)
+
+	// Response with untrusted size is rejected
+	records, err = readAll("WARC/1.1\r\nWARC-Type: response\r\nWARC-Target-URI: https://example.org/\r\n" +
+		"Content-Type: application/http; msgtype=response\r\nContent-Length: 9000000000000000000\r\n\r\nHTTP/1.1 200 OK\r\n")
+	assert.ErrorIs(t, err, errWarcRecordTooLarge)
+	assert.Empty(t, records)
+
+	// Truncated and invalid records
+	_, err = readAll("WARC/1.1\r\nWARC-Type: resource\r\nContent-Length: 9000000000000000000\r\n\r\nshort")
+	assert.NotNil(t, err)
+
+	_, err = readAll("WARC/1.1\r\nWARC-Type: resource\r\nContent-Length: -1\r\n\r\n")
+	assert.NotNil(t, err)
+
+	_, err = readAll("not a warc file\r\n")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,167 @@
+// This file is part of go-trafilatura, Go package for extracting readable
+// content, comments and metadata from a web page. Source available in
+// <https://github.com/markusmobius/go-trafilatura>.
+// Copyright (C) 2021 Markus Mobius
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or (at your
+// option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+// or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License
+// for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/markusmobius/go-trafilatura"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/semaphore"
+)
+
+func warcCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "warc [flags] [file...]",
+		Short: "Extract pages from WARC files",
+		Long: "Extract pages from WARC files, which could be compressed using gzip (WARC.gz).\n" +
+			"Only response records that contain HTML page are extracted. The extract result\n" +
+			"is written in JSON Lines format, one page per line, along with its target URI.",
+		Args: cobra.MinimumNArgs(1),
+		Run:  warcCmdHandler,
+	}
+
+	flags := cmd.Flags()
+	flags.StringP("output", "o", "", "path to output file (default stdout)")
+	flags.Int("parallel", 10, "number of concurrent extraction at a time (default 10)")
+	return cmd
+}
+
+func warcCmdHandler(cmd *cobra.Command, args []string) {
+	// Parse arguments
+	flags := cmd.Flags()
+	outputPath, _ := flags.GetString("output")
+	nThread, _ := flags.GetInt("parallel")
+	opts := createExtractorOptions(cmd)
+
+	// Prepare output
+	var output io.Writer = os.Stdout
+	if outputPath != "" {
+		f, err := os.Create(outputPath)
+		if err != nil {
+			logrus.Fatalf("failed to create output: %v", err)
+		}
+		defer f.Close()
+		output = f
+	}
+
+	// Process each WARC file
+	we := &warcExtractor{
+		extractOptions: opts,
+		semaphore:      semaphore.NewWeighted(int64(nThread)),
+		encoder:        json.NewEncoder(output),
+	}
+
+	for _, path := range args {
+		if err := we.processFile(context.Background(), path); err != nil {
+			logrus.Fatalf("failed to process %s: %v", path, err)
+		}
+	}
+}
+
+type warcExtractor struct {
+	sync.Mutex
+
+	extractOptions trafilatura.Options
+	semaphore      *semaphore.Weighted
+	encoder        *json.Encoder
+}
+
+func (we *warcExtractor) processFile(ctx context.Context, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	reader, err := newWarcReader(f)
+	if err != nil {
+		return err
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+	for {
+		record, err := reader.next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			g.Wait()
+			return err
+		}
+
+		if !record.isHTTPResponse() {
+			continue
+		}
+
+		// Acquire semaphore before starting goroutine, so we don't
+		// read too many records into memory at the same time
+		if err := we.semaphore.Acquire(ctx, 1); err != nil {
+			break
+		}
+
+		g.Go(func() error {
+			defer we.semaphore.Release(1)
+			return we.processRecord(record)
+		})
+	}
+
+	return g.Wait()
+}
+
+func (we *warcExtractor) processRecord(record *warcRecord) error {
+	// Parse the response and make sure it's a successful HTML page
+	targetURI := record.TargetURI()
+	page, err := record.page()
+	if err != nil {
+		logrus.Warnf("skipping %s: %v", targetURI, err)
+		return nil
+	}
+
+	if page.StatusCode < 200 || page.StatusCode >= 300 {
+		return nil
+	}
+
+	if contentType := page.Header.Get("Content-Type"); !strings.Contains(contentType, "text/html") {
+		return nil
+	}
+
+	// Extract the page
+	result, err := extractPage(page, we.extractOptions)
+	if err != nil {
+		logrus.Warnf("failed to extract %s: %v", targetURI, err)
+		return nil
+	}
+
+	// Write the result
+	line := jsonExtractResult(*result).toMap()
+	line["url"] = targetURI
+	line["warcDate"] = record.Header.Get("WARC-Date")
+	line["warcRecordId"] = record.Header.Get("WARC-Record-ID")
+
+	we.Lock()
+	defer we.Unlock()
+	return we.encoder.Encode(line)
+}
@@ -0,0 +1,253 @@
+// This file is part of go-trafilatura, Go package for extracting readable
+// content, comments and metadata from a web page. Source available in
+// <https://github.com/markusmobius/go-trafilatura>.
+// Copyright (C) 2021 Markus Mobius
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or (at your
+// option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+// or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License
+// for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package trafilatura
+
+import (
+	"strings"
+
+	"github.com/go-shiori/dom"
+	"github.com/markusmobius/go-trafilatura/internal/etree"
+	"github.com/markusmobius/go-trafilatura/internal/lru"
+	"golang.org/x/net/html"
+)
+
+// CodeBlock is a block of source code or preformatted text in the extracted content.
+type CodeBlock struct {
+	// Language is the language hinted by the web page, e.g. from `class="language-go"`
+	// or `data-lang` attribute. It's empty if the page doesn't specify it.
+	Language string
+
+	// Code is the content of the block, with its whitespaces kept verbatim.
+	Code string
+}
+
+// handleCodeBlock process <pre> and multi-line <code> elements. Unlike other text
+// elements, the whitespaces inside the code are kept as it is.
+func handleCodeBlock(element *html.Node, cache *lru.Cache, opts Options) *html.Node {
+	code := codeText(element)
+	language := codeLanguage(element)
+	tail := trim(etree.Tail(element))
+
+	// Mark the element and its descendants as done
+	for _, child := range etree.Iter(element) {
+		child.Data = "done"
+	}
+
+	if strings.TrimSpace(code) == "" {
+		return nil
+	}
+
+	processedElement := etree.Element("pre")
+	codeElement := etree.SubElement(processedElement, "code")
+	etree.SetText(codeElement, code)
+	if language != "" {
+		dom.SetAttribute(codeElement, "class", "language-"+language)
+	}
+
+	if opts.Deduplicate && cache != nil && duplicateTest(processedElement, cache, opts) {
+		return nil
+	}
+
+	etree.SetTail(processedElement, tail)
+	return processedElement
+}
+
+// handleCode process <code> element that found outside of <pre>. Code that
+// spans multiple lines is treated as block, otherwise as inline code.
+func handleCode(element *html.Node, cache *lru.Cache, opts Options) *html.Node {
+	if isCodeBlock(element) {
+		return handleCodeBlock(element, cache, opts)
+	}
+
+	text, tail := trim(dom.TextContent(element)), trim(etree.Tail(element))
+	for _, child := range etree.Iter(element) {
+		child.Data = "done"
+	}
+
+	if text == "" {
+		return nil
+	}
+
+	processedElement := etree.Element("p")
+	codeElement := etree.SubElement(processedElement, "code")
+	etree.SetText(codeElement, text)
+	etree.SetTail(codeElement, tail)
+	return processedElement
+}
+
+// isCodeBlock checks if the <code> element contains multiple lines of code.
+func isCodeBlock(element *html.Node) bool {
+	return strings.Contains(codeText(element), "\n")
+}
+
+// insideCode checks if element is a descendant of <pre> or <code>.
+func insideCode(element *html.Node) bool {
+	for parent := element.Parent; parent != nil; parent = parent.Parent {
+		if tagName := dom.TagName(parent); tagName == "pre" || tagName == "code" {
+			return true
+		}
+	}
+	return false
+}
+
+// codeText returns the text inside the code element verbatim, except the line breaks
+// are normalized and the empty lines at the start and the end are removed.
+func codeText(element *html.Node) string {
+	var sb strings.Builder
+	var finder func(*html.Node)
+
+	finder = func(node *html.Node) {
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			switch {
+			case child.Type == html.TextNode:
+				sb.WriteString(child.Data)
+			case child.Type == html.ElementNode && dom.TagName(child) == "br":
+				sb.WriteString("\n")
+			case child.Type == html.ElementNode:
+				finder(child)
+			}
+		}
+	}
+
+	finder(element)
+	code := strings.ReplaceAll(sb.String(), "\r\n", "\n")
+	code = strings.ReplaceAll(code, "\r", "\n")
+	code = strings.TrimRight(code, " \t\n")
+
+	// Remove leading empty lines, but keep the indentation of the first line
+	for {
+		idx := strings.Index(code, "\n")
+		if idx < 0 || strings.TrimSpace(code[:idx]) != "" {
+			break
+		}
+		code = code[idx+1:]
+	}
+
+	return code
+}
+
+// codeLanguage looks for the language hint of the code element. The hint might be
+// put in the element itself, its descendants or its nearest ancestors.
+func codeLanguage(element *html.Node) string {
+	for _, node := range etree.Iter(element, "pre", "code") {
+		if language := languageHint(node); language != "" {
+			return language
+		}
+	}
+
+	// Some sites put the hint in the wrapper, e.g. <div class="language-go"><pre>
+	parent := element.Parent
+	for i := 0; i < 3 && parent != nil && parent.Type == html.ElementNode; i++ {
+		if language := languageHint(parent); language != "" {
+			return language
+		}
+		parent = parent.Parent
+	}
+
+	return ""
+}
+
+// languageHint returns the language that specified in attributes of the element.
+func languageHint(element *html.Node) string {
+	if element == nil || element.Type != html.ElementNode {
+		return ""
+	}
+
+	for _, attr := range []string{"data-lang", "data-language"} {
+		if language := trim(dom.GetAttribute(element, attr)); language != "" {
+			return strings.ToLower(language)
+		}
+	}
+
+	for _, class := range strings.Fields(dom.GetAttribute(element, "class")) {
+		for _, prefix := range []string{"language-", "lang-"} {
+			if strings.HasPrefix(class, prefix) && len(class) > len(prefix) {
+				return strings.ToLower(class[len(prefix):])
+			}
+		}
+	}
+
+	return ""
+}
+
+// collectCodeBlocks returns the code blocks inside the extracted content.
+func collectCodeBlocks(root *html.Node) []CodeBlock {
+	var codeBlocks []CodeBlock
+	for _, pre := range etree.Iter(root, "pre") {
+		code := codeText(pre)
+		if strings.TrimSpace(code) == "" {
+			continue
+		}
+
+		codeBlocks = append(codeBlocks, CodeBlock{
+			Language: codeLanguage(pre),
+			Code:     code,
+		})
+	}
+
+	return codeBlocks
+}
+
+// contentText returns the text of extracted content. It works like IterText, then the
+// whitespaces in the text between code blocks are collapsed using trim, while the code
+// blocks are kept verbatim in their own lines.
+func contentText(root *html.Node) string {
+	var parts []string
+	var buffer strings.Builder
+	var finder func(*html.Node, int)
+	var lastLevel int
+
+	flush := func() {
+		if text := trim(buffer.String()); text != "" {
+			parts = append(parts, text)
+		}
+		buffer.Reset()
+	}
+
+	finder = func(node *html.Node, level int) {
+		switch {
+		case node.Type == html.ElementNode && dom.TagName(node) == "pre":
+			flush()
+			if code := codeText(node); strings.TrimSpace(code) != "" {
+				parts = append(parts, code)
+			}
+			lastLevel = level
+			return
+		case node.Type == html.ElementNode && dom.IsVoidElement(node):
+			buffer.WriteString(" ")
+		case node.Type == html.TextNode:
+			if level != lastLevel {
+				buffer.WriteString(" ")
+			}
+			buffer.WriteString(node.Data)
+		}
+
+		lastLevel = level
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			finder(child, level+1)
+		}
+	}
+
+	if root != nil {
+		finder(root, 0)
+	}
+
+	flush()
+	return strings.Join(parts, "\n")
+}
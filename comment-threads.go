@@ -0,0 +1,475 @@
+// This file is part of go-trafilatura, Go package for extracting readable
+// content, comments and metadata from a web page. Source available in
+// <https://github.com/markusmobius/go-trafilatura>.
+// Copyright (C) 2021 Markus Mobius
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or (at your
+// option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+// or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License
+// for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package trafilatura
+
+import (
+	"encoding/json"
+	nurl "net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-shiori/dom"
+	"golang.org/x/net/html"
+)
+
+// Comment is a single comment in the comment section of the page.
+type Comment struct {
+	// ID is the identifier of the comment in the page, e.g. "comment-12".
+	ID string
+
+	// ParentID is the ID of comment that replied by this comment. It's
+	// empty for top level comment.
+	ParentID string
+
+	// Depth is the nesting level of the comment, zero for top level comment.
+	Depth int
+
+	Author    string
+	Date      time.Time
+	Text      string
+	Permalink string
+}
+
+// commentMarkup is the rules for detecting comments in a common markup. The
+// fields are tried in order, and the first non empty result is used.
+type commentMarkup struct {
+	isComment func(*html.Node) bool
+	commentID func(*html.Node) string
+
+	// depth returns the depth of comment that declared in the markup, or -1 if
+	// it's unknown. Used when the replies are not nested inside their parent.
+	depth func(*html.Node) int
+
+	author    []string
+	date      []string
+	text      []string
+	permalink []string
+
+	// notText is the elements that removed from the text, e.g. the metadata
+	// when the text is taken from the whole comment element.
+	notText []string
+}
+
+var (
+	rxWordPressCommentID = regexp.MustCompile(`^(?:li-)?(comment-\d+)$`)
+	rxWordPressDepth     = regexp.MustCompile(`^depth-(\d+)$`)
+	rxDisqusCommentID    = regexp.MustCompile(`^dsq-comment-\d+$`)
+)
+
+var commentMarkups = []commentMarkup{
+	// Schema.org microdata
+	{
+		isComment: func(n *html.Node) bool {
+			return strings.HasSuffix(dom.GetAttribute(n, "itemtype"), "schema.org/Comment")
+		},
+		commentID: func(n *html.Node) string { return dom.GetAttribute(n, "id") },
+		author:    []string{`[itemprop="author"] [itemprop="name"]`, `[itemprop="author"]`, `[itemprop="creator"]`},
+		date:      []string{`[itemprop="dateCreated"]`, `[itemprop="datePublished"]`},
+		text:      []string{`[itemprop="text"]`},
+		permalink: []string{`[itemprop="url"]`},
+	},
+
+	// WordPress
+	{
+		isComment: func(n *html.Node) bool {
+			return rxWordPressCommentID.MatchString(dom.GetAttribute(n, "id")) &&
+				(hasClass(n, "comment") || hasClass(n, "pingback") || hasClass(n, "trackback"))
+		},
+		commentID: func(n *html.Node) string {
+			return rxWordPressCommentID.ReplaceAllString(dom.GetAttribute(n, "id"), "$1")
+		},
+		depth: func(n *html.Node) int {
+			for _, name := range strings.Fields(dom.GetAttribute(n, "class")) {
+				if parts := rxWordPressDepth.FindStringSubmatch(name); parts != nil {
+					depth, _ := strconv.Atoi(parts[1])
+					return depth - 1
+				}
+			}
+			return -1
+		},
+		author: []string{".comment-author .fn", ".comment-author cite", ".comment-author strong",
+			".comment-author b", ".comment-author"},
+		date: []string{".comment-metadata time", ".comment-meta time", ".commentmetadata time",
+			".comment-metadata a", ".comment-meta a", ".commentmetadata a"},
+		text:      []string{".comment-content", ".comment-text"},
+		permalink: []string{".comment-metadata a[href]", ".comment-meta a[href]", ".commentmetadata a[href]"},
+		notText: []string{".comment-author", ".comment-meta", ".comment-metadata", ".commentmetadata",
+			".reply", ".says", ".comment-awaiting-moderation", ".comment-likes", "footer"},
+	},
+
+	// Disqus static HTML
+	{
+		isComment: func(n *html.Node) bool { return rxDisqusCommentID.MatchString(dom.GetAttribute(n, "id")) },
+		commentID: func(n *html.Node) string { return dom.GetAttribute(n, "id") },
+		author:    []string{".dsq-comment-header cite", "cite"},
+		date:      []string{".dsq-comment-header time", "time"},
+		text:      []string{".dsq-comment-message", ".dsq-comment-body"},
+		permalink: []string{"a.dsq-comment-permalink"},
+		notText:   []string{".dsq-comment-header", ".dsq-comment-footer"},
+	},
+}
+
+// extractCommentThreads extracts each comment in the page along with its author, date
+// and position in the thread. It looks for schema.org microdata, WordPress and Disqus
+// comment markup, in that order, and falls back to the schema.org comments in JSON+LD.
+func extractCommentThreads(doc *html.Node, pageURL string) []Comment {
+	if markup, elements := detectCommentMarkup(doc); len(elements) > 0 {
+		return markup.extract(doc, elements, pageURL)
+	}
+
+	return extractJsonLdComments(doc, pageURL)
+}
+
+// detectCommentMarkup walks the document once to find the comment elements for each
+// markup, then returns the first markup that used in the page with its elements.
+func detectCommentMarkup(doc *html.Node) (commentMarkup, []*html.Node) {
+	elements := make([][]*html.Node, len(commentMarkups))
+	for _, element := range dom.GetElementsByTagName(doc, "*") {
+		if !maybeCommentElement(element) {
+			continue
+		}
+
+		for i, markup := range commentMarkups {
+			if markup.isComment(element) {
+				elements[i] = append(elements[i], element)
+			}
+		}
+	}
+
+	for i, markup := range commentMarkups {
+		if len(elements[i]) > 0 {
+			return markup, elements[i]
+		}
+	}
+
+	return commentMarkup{}, nil
+}
+
+// maybeCommentElement checks if element might be a comment in one of the markups, i.e. it
+// has item type or its ID contains "comment". Used to skip the regexes for most elements.
+func maybeCommentElement(element *html.Node) bool {
+	for _, attr := range element.Attr {
+		if attr.Key == "itemtype" || (attr.Key == "id" && strings.Contains(attr.Val, "comment")) {
+			return true
+		}
+	}
+	return false
+}
+
+// extract creates the comments from their elements, which use this markup.
+func (m commentMarkup) extract(doc *html.Node, elements []*html.Node, pageURL string) []Comment {
+	var comments []Comment
+	indexes := make(map[*html.Node]int)
+
+	for _, element := range elements {
+		comment := Comment{
+			ID:     m.commentID(element),
+			Author: m.field(element, m.author, authorValue),
+			Date:   parseCommentDate(m.field(element, m.date, dateValue)),
+			Text:   m.commentText(element),
+		}
+
+		if comment.ID == "" {
+			comment.ID = "comment-" + strconv.Itoa(len(comments)+1)
+		}
+
+		comment.Permalink = m.field(element, m.permalink, urlValue)
+		if comment.Permalink == "" && dom.GetAttribute(element, "id") != "" {
+			comment.Permalink = "#" + dom.GetAttribute(element, "id")
+		}
+		comment.Permalink = resolveCommentURL(comment.Permalink, pageURL)
+
+		// Find the parent, i.e. the nearest ancestor which is a comment. If there
+		// are none, use the depth from markup to find it in the previous comments.
+		if parent := m.parentComment(element, doc); parent != nil {
+			if idx, exist := indexes[parent]; exist {
+				comment.ParentID = comments[idx].ID
+				comment.Depth = comments[idx].Depth + 1
+			}
+		} else if m.depth != nil {
+			if depth := m.depth(element); depth > 0 {
+				for i := len(comments) - 1; i >= 0; i-- {
+					if comments[i].Depth == depth-1 {
+						comment.ParentID = comments[i].ID
+						comment.Depth = depth
+						break
+					}
+				}
+			}
+		}
+
+		indexes[element] = len(comments)
+		comments = append(comments, comment)
+	}
+
+	return comments
+}
+
+// parentComment returns the nearest ancestor of element which is a comment.
+func (m commentMarkup) parentComment(element, root *html.Node) *html.Node {
+	for parent := element.Parent; parent != nil && parent != root; parent = parent.Parent {
+		if parent.Type == html.ElementNode && m.isComment(parent) {
+			return parent
+		}
+	}
+	return nil
+}
+
+// ownElements returns the elements inside the comment that matched with the
+// selector, excluding the ones that belong to the nested replies.
+func (m commentMarkup) ownElements(element *html.Node, selector string) []*html.Node {
+	var results []*html.Node
+	for _, match := range dom.QuerySelectorAll(element, selector) {
+		if m.parentComment(match, nil) == element {
+			results = append(results, match)
+		}
+	}
+	return results
+}
+
+// field returns the first non empty value from the elements that matched with the selectors.
+func (m commentMarkup) field(element *html.Node, selectors []string, value func(*html.Node) string) string {
+	for _, selector := range selectors {
+		for _, match := range m.ownElements(element, selector) {
+			if result := value(match); result != "" {
+				return result
+			}
+		}
+	}
+	return ""
+}
+
+// commentText returns the text of the comment, rendered as plain text.
+func (m commentMarkup) commentText(element *html.Node) string {
+	content := element
+	for _, selector := range m.text {
+		if matches := m.ownElements(element, selector); len(matches) > 0 {
+			content = matches[0]
+			break
+		}
+	}
+
+	// Remove the replies and metadata
+	clone := dom.Clone(content, true)
+	for _, child := range dom.GetElementsByTagName(clone, "*") {
+		if m.isComment(child) && child.Parent != nil {
+			child.Parent.RemoveChild(child)
+		}
+	}
+
+	for _, selector := range m.notText {
+		dom.RemoveNodes(dom.QuerySelectorAll(clone, selector), nil)
+	}
+
+	return RenderText(clone, TextOptions{})
+}
+
+// textValue returns the text of element, or the content of <meta>.
+func textValue(element *html.Node) string {
+	if dom.TagName(element) == "meta" {
+		return trim(dom.GetAttribute(element, "content"))
+	}
+	return trim(dom.TextContent(element))
+}
+
+// authorValue returns the first line in the text of element, since
+// the name might be followed by badge or date in the next line.
+func authorValue(element *html.Node) string {
+	text := strings.TrimSpace(RenderText(element, TextOptions{}))
+	if idx := strings.Index(text, "\n"); idx >= 0 {
+		text = text[:idx]
+	}
+	return trim(text)
+}
+
+// dateValue returns the machine readable date of element, or its text.
+func dateValue(element *html.Node) string {
+	for _, attr := range []string{"datetime", "content"} {
+		if value := trim(dom.GetAttribute(element, attr)); value != "" {
+			return value
+		}
+	}
+	return trim(dom.TextContent(element))
+}
+
+// urlValue returns the URL that referenced by element.
+func urlValue(element *html.Node) string {
+	for _, attr := range []string{"href", "content", "src"} {
+		if value := trim(dom.GetAttribute(element, attr)); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// hasClass checks if element has the specified class name.
+func hasClass(element *html.Node, className string) bool {
+	for _, name := range strings.Fields(dom.GetAttribute(element, "class")) {
+		if name == className {
+			return true
+		}
+	}
+	return false
+}
+
+var commentDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05-0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	time.RFC1123Z,
+	time.RFC1123,
+	"January 2, 2006 at 3:04 pm",
+	"January 2, 2006 at 15:04",
+	"January 2, 2006 at 3:04pm",
+	"January 2, 2006",
+	"Jan 2, 2006 at 3:04 pm",
+	"Jan 2, 2006",
+	"02.01.2006 15:04",
+	"02.01.2006",
+	"2 January 2006 at 15:04",
+	"2 January 2006",
+}
+
+// parseCommentDate parses the date of comment. It returns zero time if the date
+// is not in one of the common formats.
+func parseCommentDate(value string) time.Time {
+	value = trim(value)
+	if value == "" {
+		return time.Time{}
+	}
+
+	for _, layout := range commentDateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+// resolveCommentURL converts the permalink of comment into absolute URL.
+func resolveCommentURL(link string, pageURL string) string {
+	if link == "" || pageURL == "" {
+		return link
+	}
+
+	base, err := nurl.Parse(pageURL)
+	if err != nil {
+		return link
+	}
+
+	if strings.HasPrefix(link, "#") {
+		base.Fragment = ""
+		return base.String() + link
+	}
+
+	return createAbsoluteURL(link, base)
+}
+
+// extractJsonLdComments extracts the schema.org comments from JSON+LD data.
+func extractJsonLdComments(doc *html.Node, pageURL string) []Comment {
+	var comments []Comment
+
+	var addComment func(obj map[string]interface{}, parent *Comment)
+	var findComments func(data interface{}, parent *Comment)
+
+	addComment = func(obj map[string]interface{}, parent *Comment) {
+		comment := Comment{
+			ID:        jsonString(obj["@id"]),
+			Author:    trim(jsonName(obj["author"])),
+			Date:      parseCommentDate(strOr(jsonString(obj["dateCreated"]), jsonString(obj["datePublished"]))),
+			Text:      trim(strOr(jsonString(obj["text"]), jsonString(obj["description"]))),
+			Permalink: resolveCommentURL(jsonString(obj["url"]), pageURL),
+		}
+
+		if comment.ID == "" {
+			comment.ID = "comment-" + strconv.Itoa(len(comments)+1)
+		}
+
+		if parent != nil {
+			comment.ParentID = parent.ID
+			comment.Depth = parent.Depth + 1
+		}
+
+		comments = append(comments, comment)
+		findComments(obj["comment"], &comment)
+	}
+
+	findComments = func(data interface{}, parent *Comment) {
+		switch value := data.(type) {
+		case []interface{}:
+			for _, item := range value {
+				findComments(item, parent)
+			}
+		case map[string]interface{}:
+			if jsonString(value["@type"]) == "Comment" {
+				addComment(value, parent)
+				return
+			}
+
+			// Sort the keys, so the comments are always in the same order
+			keys := make([]string, 0, len(value))
+			for key := range value {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+
+			for _, key := range keys {
+				findComments(value[key], parent)
+			}
+		}
+	}
+
+	for _, script := range dom.QuerySelectorAll(doc, `script[type="application/ld+json"]`) {
+		var data interface{}
+		if err := json.Unmarshal([]byte(dom.TextContent(script)), &data); err == nil {
+			findComments(data, nil)
+		}
+	}
+
+	return comments
+}
+
+// jsonString returns the value if it's a string.
+func jsonString(value interface{}) string {
+	str, _ := value.(string)
+	return str
+}
+
+// jsonName returns the value if it's a string, or its name if it's an object.
+func jsonName(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case map[string]interface{}:
+		return jsonString(v["name"])
+	case []interface{}:
+		if len(v) > 0 {
+			return jsonName(v[0])
+		}
+	}
+	return ""
+}
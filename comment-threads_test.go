@@ -0,0 +1,175 @@
+// This file is part of go-trafilatura, Go package for extracting readable
+// content, comments and metadata from a web page. Source available in
+// <https://github.com/markusmobius/go-trafilatura>.
+// Copyright (C) 2021 Markus Mobius
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or (at your
+// option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+// or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License
+// for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package trafilatura
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CommentThreads(t *testing.T) {
+	// WordPress nested comments
+	doc := docFromStr(`<html><body><article><p>Post</p></article>
+		<ol class="comment-list">
+			<li id="comment-1" class="comment depth-1">
+				<article id="div-comment-1" class="comment-body">
+					<footer class="comment-meta">
+						<div class="comment-author vcard"><b class="fn">Alice</b> <span class="says">says:</span></div>
+						<div class="comment-metadata"><a href="/post/#comment-1"><time datetime="2021-05-03T10:15:00+00:00">May 3, 2021 at 10:15 am</time></a></div>
+					</footer>
+					<div class="comment-content"><p>First comment.</p></div>
+					<div class="reply"><a class="comment-reply-link" href="#">Reply</a></div>
+				</article>
+				<ol class="children">
+					<li id="comment-2" class="comment depth-2">
+						<article class="comment-body">
+							<footer class="comment-meta">
+								<div class="comment-author vcard"><b class="fn">Bob</b></div>
+								<div class="comment-metadata"><a href="/post/#comment-2"><time datetime="2021-05-04">May 4, 2021</time></a></div>
+							</footer>
+							<div class="comment-content"><p>A reply.</p></div>
+						</article>
+					</li>
+				</ol>
+			</li>
+			<li id="comment-3" class="comment depth-1">
+				<div class="comment-author"><cite class="fn">Carol<br/><span>Author</span></cite></div>
+				<div class="comment-content"><p>Second comment.</p></div>
+			</li>
+		</ol></body></html>`)
+
+	comments := extractCommentThreads(doc, "https://example.org/post/")
+	assert.Len(t, comments, 3)
+	assert.Equal(t, "comment-1", comments[0].ID)
+	assert.Equal(t, "", comments[0].ParentID)
+	assert.Equal(t, "Alice", comments[0].Author)
+	assert.True(t, comments[0].Date.Equal(time.Date(2021, 5, 3, 10, 15, 0, 0, time.UTC)))
+	assert.Equal(t, "First comment.", comments[0].Text)
+	assert.Equal(t, "https://example.org/post/#comment-1", comments[0].Permalink)
+	assert.Equal(t, "comment-2", comments[1].ID)
+	assert.Equal(t, "comment-1", comments[1].ParentID)
+	assert.Equal(t, 1, comments[1].Depth)
+	assert.Equal(t, "Bob", comments[1].Author)
+	assert.Equal(t, "A reply.", comments[1].Text)
+	assert.Equal(t, "Carol", comments[2].Author)
+	assert.Equal(t, "", comments[2].ParentID)
+	assert.True(t, comments[2].Date.IsZero())
+	assert.Equal(t, "https://example.org/post/#comment-3", comments[2].Permalink)
+
+	// Replies that are not nested inside their parent use the depth class
+	doc = docFromStr(`<html><body><ol>
+		<li id="li-comment-7" class="comment depth-1"><div class="comment-content">Parent</div></li>
+		<ul class="children"><li id="li-comment-8" class="comment depth-2"><div class="comment-content">Child</div></li></ul>
+		</ol></body></html>`)
+
+	comments = extractCommentThreads(doc, "")
+	assert.Len(t, comments, 2)
+	assert.Equal(t, "comment-8", comments[1].ID)
+	assert.Equal(t, "comment-7", comments[1].ParentID)
+	assert.Equal(t, 1, comments[1].Depth)
+	assert.Equal(t, "#li-comment-8", comments[1].Permalink)
+
+	// Schema.org microdata
+	doc = docFromStr(`<html><body>
+		<div itemscope itemtype="https://schema.org/Comment" id="c1">
+			<span itemprop="author" itemscope itemtype="https://schema.org/Person"><span itemprop="name">Dan</span></span>
+			<meta itemprop="dateCreated" content="2020-01-02">
+			<div itemprop="text">Nice article.</div>
+			<div itemscope itemtype="https://schema.org/Comment" id="c2">
+				<span itemprop="author">Eve</span>
+				<div itemprop="text">Thanks!</div>
+			</div>
+		</div></body></html>`)
+
+	comments = extractCommentThreads(doc, "")
+	assert.Len(t, comments, 2)
+	assert.Equal(t, "Dan", comments[0].Author)
+	assert.Equal(t, time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC), comments[0].Date)
+	assert.Equal(t, "Nice article.", comments[0].Text)
+	assert.Equal(t, "Eve", comments[1].Author)
+	assert.Equal(t, "c1", comments[1].ParentID)
+
+	// Disqus static HTML
+	doc = docFromStr(`<html><body><ul id="dsq-comments">
+		<li id="dsq-comment-42">
+			<div class="dsq-comment-header"><cite>Frank</cite></div>
+			<div class="dsq-comment-body"><div class="dsq-comment-message"><p>Hello there.</p></div></div>
+			<ul class="children"><li id="dsq-comment-43">
+				<div class="dsq-comment-header"><cite>Grace</cite></div>
+				<div class="dsq-comment-message">Hi.</div>
+			</li></ul>
+		</li></ul></body></html>`)
+
+	comments = extractCommentThreads(doc, "")
+	assert.Len(t, comments, 2)
+	assert.Equal(t, "Frank", comments[0].Author)
+	assert.Equal(t, "Hello there.", comments[0].Text)
+	assert.Equal(t, "dsq-comment-42", comments[1].ParentID)
+	assert.Equal(t, "Grace", comments[1].Author)
+
+	// Microdata is preferred when the page uses several markups
+	doc = docFromStr(`<html><body>
+		<li id="comment-7" class="comment"><div class="comment-content">WordPress comment.</div></li>
+		<div itemscope itemtype="https://schema.org/Comment" id="c3"><div itemprop="text">Microdata comment.</div></div>
+		</body></html>`)
+
+	markup, elements := detectCommentMarkup(doc)
+	assert.Len(t, elements, 1)
+	assert.Equal(t, "c3", markup.commentID(elements[0]))
+
+	_, elements = detectCommentMarkup(docFromStr(`<html><body><div id="comments"><p>No comments yet.</p></div></body></html>`))
+	assert.Empty(t, elements)
+
+	// JSON+LD
+	doc = docFromStr(`<html><head><script type="application/ld+json">{
+		"@context": "https://schema.org", "@type": "BlogPosting",
+		"comment": [{"@type": "Comment", "author": {"@type": "Person", "name": "Heidi"},
+			"dateCreated": "2019-07-08T09:10:11Z", "text": "Great.", "url": "/post#c9",
+			"comment": {"@type": "Comment", "author": "Ivan", "text": "Agreed."}}]
+	}</script></head><body></body></html>`)
+
+	comments = extractCommentThreads(doc, "https://example.org/post")
+	assert.Len(t, comments, 2)
+	assert.Equal(t, "Heidi", comments[0].Author)
+	assert.True(t, comments[0].Date.Equal(time.Date(2019, 7, 8, 9, 10, 11, 0, time.UTC)))
+	assert.Equal(t, "https://example.org/post#c9", comments[0].Permalink)
+	assert.Equal(t, "Ivan", comments[1].Author)
+	assert.Equal(t, comments[0].ID, comments[1].ParentID)
+	assert.Equal(t, 1, comments[1].Depth)
+
+	// Extract keeps the old comment outputs and adds the threads
+	rawHTML := `<html><body><article><p>` + strings.Repeat("Lorem ipsum dolor sit amet. ", 20) + `</p></article>
+		<div id="comments"><ol class="commentlist">
+		<li id="comment-5" class="comment"><div class="comment-author"><cite class="fn">Judy</cite></div>
+		<div class="comment-content"><p>` + strings.Repeat("Consectetur adipiscing elit. ", 5) + `</p></div></li>
+		</ol></div></body></html>`
+
+	result, err := Extract(strings.NewReader(rawHTML), Options{NoFallback: true})
+	assert.Nil(t, err)
+	assert.Len(t, result.Comments, 1)
+	assert.Equal(t, "Judy", result.Comments[0].Author)
+	assert.Contains(t, result.CommentsText, "Consectetur adipiscing elit.")
+
+	result, err = Extract(strings.NewReader(rawHTML), Options{NoFallback: true, ExcludeComments: true})
+	assert.Nil(t, err)
+	assert.Empty(t, result.Comments)
+}
@@ -23,6 +23,8 @@ package trafilatura
 
 import (
 	nurl "net/url"
+	"regexp"
+	"time"
 
 	"github.com/markusmobius/go-htmldate"
 	"golang.org/x/net/html"
@@ -43,6 +45,11 @@ type Config struct {
 	MinExtractedCommentSize int
 	MinOutputSize           int
 	MinOutputCommentSize    int
+
+	// CJKCharWeight is the number of characters that each Chinese, Japanese or
+	// Korean character counted as when comparing text length with the size
+	// settings above. If it's zero or negative, it's counted as one character.
+	CJKCharWeight int
 }
 
 // DefaultConfig returns the default configuration value.
@@ -56,6 +63,8 @@ func DefaultConfig() *Config {
 		MinExtractedCommentSize: 10,
 		MinOutputSize:           10,
 		MinOutputCommentSize:    10,
+
+		CJKCharWeight: 2,
 	}
 }
 
@@ -68,6 +77,11 @@ type Options struct {
 	// OriginalURL is the original URL of the page. Might be overwritten by URL in metadata.
 	OriginalURL *nurl.URL
 
+	// ContentType is the value of Content-Type header that returned by server along with
+	// the page. If it specifies charset, it will be used to decode the page. Only used
+	// when extracting using `Extract`.
+	ContentType string
+
 	// TargetLanguage is ISO 639-1 language code to make the extractor only process web page that
 	// uses the specified language.
 	TargetLanguage string
@@ -93,6 +107,11 @@ type Options struct {
 	// targets (experimental).
 	IncludeLinks bool
 
+	// MediaPlaceholders specify whether to leave placeholder paragraphs in the content for
+	// the embedded media like video, audio and social posts, which otherwise removed along
+	// with their players. The text of placeholder is also recorded in `ExtractResult.Media`.
+	MediaPlaceholders bool
+
 	// Deduplicate specify whether to remove duplicate segments and sections.
 	Deduplicate bool
 
@@ -104,10 +123,46 @@ type Options struct {
 	// Document that surpass this value will be discarded.
 	MaxTreeSize int
 
+	// MaxInputBytes is the max number of bytes that read from the input. Only used
+	// when extracting using `Extract`. If it's zero or negative, there are no limit.
+	MaxInputBytes int64
+
+	// MaxNodes is the max number of nodes in the parsed document, checked before the
+	// extraction started. If it's zero or negative, there are no limit.
+	MaxNodes int
+
+	// MaxDepth is the max nesting depth of the parsed document, checked before the
+	// extraction started. If it's zero or negative, there are no limit.
+	MaxDepth int
+
+	// MaxDuration is the time budget for the extraction. When using `Extract`, it
+	// includes the time for parsing the document. The budget is checked between the
+	// stages of extraction, and the fallback extractors are skipped once it runs out.
+	// If it's zero or negative, there are no limit.
+	MaxDuration time.Duration
+
 	// EnableLog specify whether log should be enabled or not.
 	EnableLog bool
 
+	// Metrics is the optional hook that receives measurements from the extraction pipeline,
+	// e.g. the outcome of extraction, fallback usage and duration of each stage.
+	Metrics MetricsHook
+
 	// HtmlDateOptions is configuration for the external `htmldate` package that used to look
 	// for publish date of a web page.
 	HtmlDateOptions *htmldate.Options
+
+	// BoilerplatePhrases is list of phrases for boilerplate text like share and print buttons,
+	// grouped by ISO 639-1 language code. The list is chosen using language that declared in
+	// the page, `TargetLanguage` or the detected language (English and German if it's unknown),
+	// and line that only contains one of its phrases will be removed. Phrases with empty language code are used for every page,
+	// and line that ends with one of them will be removed. If it's nil, the phrases from
+	// `DefaultBoilerplatePhrases` are used.
+	BoilerplatePhrases map[string][]string
+
+	// deadline is the time when budget for extraction runs out.
+	deadline time.Time
+
+	// rxBoilerplate is the filter for boilerplate text in the page.
+	rxBoilerplate *regexp.Regexp
 }
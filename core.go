@@ -23,10 +23,12 @@ package trafilatura
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	nurl "net/url"
 	"strings"
+	"time"
 	"unicode/utf8"
 
 	"github.com/abadojack/whatlanggo"
@@ -44,24 +46,135 @@ type ExtractResult struct {
 	ContentText  string
 	CommentsText string
 	Metadata     Metadata
+
+	// Comments is the comments in the page, along with their author, date and
+	// position in the thread. Only detected from common comment markup, i.e.
+	// WordPress, Disqus and schema.org, so it might be empty even though there
+	// are comments in CommentsNode.
+	Comments []Comment
+
+	// Media is the videos, audios, social posts and other media that embedded in the
+	// page, including the ones that removed from the content.
+	Media []Media
+
+	// Footnotes is the footnotes and references that linked from the content. Their
+	// markers are kept in the content as <sup>, e.g. "[1]", which wraps a link to
+	// "#" + ID when the links are included.
+	Footnotes []Footnote
+
+	// CodeBlocks is the code blocks and preformatted texts found in the content.
+	CodeBlocks []CodeBlock
+
+	// Encoding is the name of character encoding that detected in the
+	// original document. Only set when extracting using `Extract`.
+	Encoding string
 }
 
-// Extract parses a reader and find the main readable content.
-func Extract(r io.Reader, opts Options) (*ExtractResult, error) {
+// Extract parses a reader and find the main readable content. If the extraction
+// panics, including while decoding and parsing the document, the panic is recovered
+// and returned as *PanicError.
+func Extract(r io.Reader, opts Options) (result *ExtractResult, err error) {
+	defer recoverPanic(opts, &result, &err)
+
+	// Start the time budget here, so it includes the parsing time
+	start := time.Now()
+	if opts.MaxDuration > 0 {
+		opts.deadline = start.Add(opts.MaxDuration)
+	}
+
+	// Count the size of document if metrics is enabled
+	if opts.Metrics != nil {
+		counter := &countingReader{Reader: r}
+		defer func() { observeSize(opts, SizeInputBytes, counter.N) }()
+		r = counter
+	}
+
+	// Limit the size of document
+	if opts.MaxInputBytes > 0 {
+		r = newLimitedReader(r, opts.MaxInputBytes)
+	}
+
+	// Convert the document into UTF-8
+	newReader, encodingName, err := decodeHTML(r, opts.ContentType)
+	if err != nil {
+		var limitErr *LimitError
+		if errors.As(err, &limitErr) {
+			observeOutcome(opts, OutcomeLimitExceeded)
+		} else {
+			observeOutcome(opts, OutcomeInvalidDocument)
+		}
+		return nil, err
+	}
+
+	// Make sure the document is not too large before building its tree
+	if err := checkTokenLimits(newReader(), opts); err != nil {
+		observeOutcome(opts, OutcomeLimitExceeded)
+		return nil, err
+	}
+
 	// Parse HTML
-	doc, err := dom.Parse(r)
+	doc, err := html.Parse(newReader())
 	if err != nil {
+		observeOutcome(opts, OutcomeInvalidDocument)
 		return nil, err
 	}
+	observeStage(opts, StageParsing, start)
 
-	return ExtractDocument(doc, opts)
+	// Since the document is parsed here, the extraction can modify it directly. However,
+	// the fallback extractors need the untouched document. If they will be used, the
+	// document is cloned before extraction. If not, the document will be parsed again
+	// in the rare case the baseline extraction is needed.
+	if !opts.NoFallback || len(opts.FallbackCandidates) > 0 {
+		result, err = extractDocumentWithLimits(doc, true, func() *html.Node { return doc }, opts)
+	} else {
+		result, err = extractDocumentWithLimits(doc, false, func() *html.Node {
+			backup, _ := html.Parse(newReader())
+			return backup
+		}, opts)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	result.Encoding = encodingName
+	return result, nil
 }
 
 // ExtractDocument parses the specified document and find the main readable content.
-func ExtractDocument(doc *html.Node, opts Options) (*ExtractResult, error) {
-	// Clone document to make sure the original kept untouched
-	doc = dom.Clone(doc, true)
+// If the extraction panics, the panic is recovered and returned as *PanicError.
+func ExtractDocument(doc *html.Node, opts Options) (result *ExtractResult, err error) {
+	defer recoverPanic(opts, &result, &err)
+
+	// The extraction works on the clone of document, so the original is kept
+	// untouched and can be used by the fallback extractors.
+	return extractDocumentWithLimits(doc, true, func() *html.Node { return doc }, opts)
+}
+
+// extractDocumentWithLimits checks the document limits, then extracts it. If clone
+// is true, the extraction works on the clone of document, otherwise the document will
+// be modified. The backup function returns the untouched document for the fallback
+// extractors, and only called if the fallback is needed.
+func extractDocumentWithLimits(doc *html.Node, clone bool, backup func() *html.Node, opts Options) (*ExtractResult, error) {
+	// Start the time budget, unless it's already started by `Extract`
+	if opts.MaxDuration > 0 && opts.deadline.IsZero() {
+		opts.deadline = time.Now().Add(opts.MaxDuration)
+	}
+
+	// Make sure the document is not too large before processing it
+	if err := checkTreeLimits(doc, opts); err != nil {
+		observeOutcome(opts, OutcomeLimitExceeded)
+		return nil, err
+	}
+
+	if clone {
+		doc = dom.Clone(doc, true)
+	}
 
+	return extractDocument(doc, backup, opts)
+}
+
+func extractDocument(doc *html.Node, backup func() *html.Node, opts Options) (*ExtractResult, error) {
 	//  Set default config
 	if opts.Config == nil {
 		opts.Config = DefaultConfig()
@@ -72,27 +185,39 @@ func ExtractDocument(doc *html.Node, opts Options) (*ExtractResult, error) {
 
 	// HTML language check
 	if opts.TargetLanguage != "" && !checkHtmlLanguage(doc, opts) {
+		observeOutcome(opts, OutcomeWrongLanguage)
 		return nil, fmt.Errorf("web page language is not %s", opts.TargetLanguage)
 	}
 
-	// Backup the doc first
-	docBackup := dom.Clone(doc, true)
+	// Prepare filter for boilerplate text
+	opts.rxBoilerplate = boilerplateFilter(doc, opts)
 
 	// Fetch metadata
+	start := time.Now()
 	metadata := extractMetadata(doc, opts)
+	observeStage(opts, StageMetadata, start)
+
+	// Check the time budget
+	if err := checkDeadline(opts); err != nil {
+		observeOutcome(opts, OutcomeLimitExceeded)
+		return nil, err
+	}
 
 	// Check if essential metadata is missing
 	if opts.HasEssentialMetadata {
-		if metadata.Title == "" {
-			return nil, fmt.Errorf("title is required")
-		}
-
-		if metadata.URL == "" {
-			return nil, fmt.Errorf("url is required")
+		var err error
+		switch {
+		case metadata.Title == "":
+			err = fmt.Errorf("title is required")
+		case metadata.URL == "":
+			err = fmt.Errorf("url is required")
+		case metadata.Date.IsZero():
+			err = fmt.Errorf("date is required")
 		}
 
-		if metadata.Date.IsZero() {
-			return nil, fmt.Errorf("date is required")
+		if err != nil {
+			observeOutcome(opts, OutcomeMissingMetadata)
+			return nil, err
 		}
 	}
 
@@ -105,8 +230,24 @@ func ExtractDocument(doc *html.Node, opts Options) (*ExtractResult, error) {
 		}
 	}
 
+	// Extract comment threads, media and footnotes before the markup is cleaned
+	pageURL := metadata.URL
+	if pageURL == "" && opts.OriginalURL != nil {
+		pageURL = opts.OriginalURL.String()
+	}
+
+	var comments []Comment
+	if !opts.ExcludeComments {
+		comments = extractCommentThreads(doc, pageURL)
+	}
+
+	media := collectMedia(doc, pageURL, opts)
+	footnotes := extractFootnotes(doc)
+
 	// Clean document
+	start = time.Now()
 	docCleaning(doc, opts.ExcludeTables, opts.IncludeImages)
+	observeStage(opts, StageCleaning, start)
 
 	// TODO: Here in original Trafilatura, we are supposed to convert HTML tags
 	// into the one that suitable for XML. However, since we prefer the results
@@ -118,33 +259,54 @@ func ExtractDocument(doc *html.Node, opts Options) (*ExtractResult, error) {
 	var commentsBody *html.Node
 
 	if !opts.ExcludeComments {
+		start = time.Now()
 		commentsBody, tmpComments = extractComments(doc, cache, opts)
-		lenComments = utf8.RuneCountInString(tmpComments)
+		lenComments = weightedLength(tmpComments, opts.Config)
+		observeStage(opts, StageComments, start)
 	}
 
 	// Extract content
+	start = time.Now()
 	postBody, tmpBodyText, sureThing := extractContent(doc, cache, opts)
+	observeStage(opts, StageContent, start)
+
+	// Check the time budget
+	if err := checkDeadline(opts); err != nil {
+		observeOutcome(opts, OutcomeLimitExceeded)
+		return nil, err
+	}
 
 	// Use fallback if necessary
+	start = time.Now()
 	if !opts.NoFallback || len(opts.FallbackCandidates) > 0 {
+		docBackup := backup()
 		postBody, tmpBodyText = compareExtraction(docBackup, postBody, opts)
 		// Add baseline as additional fallback
 		if len(dom.Children(postBody)) == 0 {
 			postBody, tmpBodyText = baseline(docBackup)
+			observeFallback(opts, FallbackBaseline)
 		}
 	} else {
 		// Rescue: try to use original/dirty tree
-		lenText := utf8.RuneCountInString(tmpBodyText)
+		lenText := weightedLength(tmpBodyText, opts.Config)
 		if !sureThing && (opts.Config.MinExtractedSize == 0 || lenText < opts.Config.MinExtractedSize) {
-			baselineBody, baselineText := baseline(docBackup)
+			baselineBody, baselineText := baseline(backup())
 
 			// Make sure baseline is not worse than the original
-			lenBaselineText := utf8.RuneCountInString(baselineText)
+			lenBaselineText := weightedLength(baselineText, opts.Config)
 			if lenBaselineText > lenText {
 				postBody, tmpBodyText = baselineBody, baselineText
+				observeFallback(opts, FallbackBaseline)
 			}
 		}
 	}
+	observeStage(opts, StageFallback, start)
+
+	// Check the time budget
+	if err := checkDeadline(opts); err != nil {
+		observeOutcome(opts, OutcomeLimitExceeded)
+		return nil, err
+	}
 
 	// Tree size sanity check
 	if opts.MaxTreeSize > 0 {
@@ -154,6 +316,7 @@ func ExtractDocument(doc *html.Node, opts Options) (*ExtractResult, error) {
 			}
 
 			if nChildren := len(dom.Children(postBody)); nChildren > opts.MaxTreeSize {
+				observeOutcome(opts, OutcomeTreeTooLarge)
 				return nil, fmt.Errorf("output tree to long, discarding file : %d", nChildren)
 			}
 		}
@@ -164,13 +327,15 @@ func ExtractDocument(doc *html.Node, opts Options) (*ExtractResult, error) {
 		logWarn(opts, "not enough comments: %s", opts.OriginalURL)
 	}
 
-	lenText := utf8.RuneCountInString(tmpBodyText)
+	lenText := weightedLength(tmpBodyText, opts.Config)
 	if lenText < opts.Config.MinOutputSize && lenComments < opts.Config.MinOutputCommentSize {
+		observeOutcome(opts, OutcomeTooShort)
 		return nil, fmt.Errorf("text and comments are not long enough: %d %d", lenText, lenComments)
 	}
 
 	// Check duplicates at body level
 	if opts.Deduplicate && duplicateTest(postBody, cache, opts) {
+		observeOutcome(opts, OutcomeDuplicate)
 		return nil, fmt.Errorf("extracted body has been duplicated")
 	}
 
@@ -178,15 +343,22 @@ func ExtractDocument(doc *html.Node, opts Options) (*ExtractResult, error) {
 	if opts.TargetLanguage != "" {
 		lang := getLanguage(tmpBodyText, tmpComments)
 		if lang != opts.TargetLanguage {
+			observeOutcome(opts, OutcomeWrongLanguage)
 			return nil, fmt.Errorf("wrong language, want %s got %s", opts.TargetLanguage, lang)
 		}
 	}
 
 	// Post cleaning
+	start = time.Now()
 	postCleaning(postBody)
 	if commentsBody != nil {
 		postCleaning(commentsBody)
 	}
+	observeStage(opts, StagePostProcessing, start)
+
+	observeOutcome(opts, OutcomeSuccess)
+	observeSize(opts, SizeContentChars, lenText)
+	observeSize(opts, SizeCommentsChars, lenComments)
 
 	return &ExtractResult{
 		ContentNode:  postBody,
@@ -194,6 +366,10 @@ func ExtractDocument(doc *html.Node, opts Options) (*ExtractResult, error) {
 		CommentsNode: commentsBody,
 		CommentsText: tmpComments,
 		Metadata:     metadata,
+		CodeBlocks:   collectCodeBlocks(postBody),
+		Comments:     comments,
+		Media:        media,
+		Footnotes:    footnotes,
 	}, nil
 }
 
@@ -207,6 +383,11 @@ func extractComments(doc *html.Node, cache *lru.Cache, opts Options) (*html.Node
 
 	// Process each selector rules
 	for _, rule := range selector.CommentsRules {
+		// Stop if time budget runs out
+		if deadlineExceeded(opts) {
+			break
+		}
+
 		// Capture first node that matched with the rule
 		var subTree *html.Node
 		for _, n := range dom.GetElementsByTagName(doc, "*") {
@@ -289,6 +470,11 @@ func extractContent(doc *html.Node, cache *lru.Cache, opts Options) (*html.Node,
 
 	// Iterate each selector rule
 	for _, rule := range selector.ContentRules {
+		// Stop if time budget runs out
+		if deadlineExceeded(opts) {
+			break
+		}
+
 		// Capture first node that matched with the rule
 		var subTree *html.Node
 		for _, n := range dom.GetElementsByTagName(doc, "*") {
@@ -307,16 +493,16 @@ func extractContent(doc *html.Node, cache *lru.Cache, opts Options) (*html.Node,
 		pruneUnwantedNodes(subTree, selector.DiscardedContentRules)
 
 		// Remove elements by link density
-		deleteByLinkDensity(subTree, "div", true)
-		deleteByLinkDensity(subTree, "ul", false)
-		deleteByLinkDensity(subTree, "ol", false)
-		deleteByLinkDensity(subTree, "dl", false)
-		deleteByLinkDensity(subTree, "p", false)
+		deleteByLinkDensity(subTree, "div", true, opts)
+		deleteByLinkDensity(subTree, "ul", false, opts)
+		deleteByLinkDensity(subTree, "ol", false, opts)
+		deleteByLinkDensity(subTree, "dl", false, opts)
+		deleteByLinkDensity(subTree, "p", false, opts)
 
 		// Define iteration strategy
 		if _, exist := potentialTags["table"]; exist {
 			for _, table := range etree.Iter(subTree, "table") {
-				if linkDensityTestTables(table) {
+				if linkDensityTestTables(table, opts) {
 					etree.Remove(table)
 				}
 			}
@@ -337,7 +523,7 @@ func extractContent(doc *html.Node, cache *lru.Cache, opts Options) (*html.Node,
 			}
 		}
 
-		if utf8.RuneCountInString(paragraphText) < opts.Config.MinExtractedSize*2 {
+		if weightedLength(paragraphText, opts.Config) < opts.Config.MinExtractedSize*2 {
 			potentialTags["div"] = struct{}{}
 		}
 
@@ -377,12 +563,12 @@ func extractContent(doc *html.Node, cache *lru.Cache, opts Options) (*html.Node,
 	}
 
 	// Try parsing wild <p> elements if nothing found or text too short
-	tmpText := trim(etree.IterText(resultBody, " "))
-	tmpTextLength := utf8.RuneCountInString(tmpText)
+	tmpText := contentText(resultBody)
+	tmpTextLength := weightedLength(tmpText, opts.Config)
 
 	if len(dom.Children(resultBody)) == 0 || tmpTextLength < opts.Config.MinExtractedSize {
 		recoverWildText(doc, resultBody, potentialTags, cache, opts)
-		tmpText = trim(etree.IterText(resultBody, " "))
+		tmpText = contentText(resultBody)
 	} else {
 		sureThing = true
 	}
@@ -396,12 +582,12 @@ func extractContent(doc *html.Node, cache *lru.Cache, opts Options) (*html.Node,
 
 // deleteByLinkDensity determines the link density of elements with respect to
 // their length, and remove the elements identified as boilerplate.
-func deleteByLinkDensity(subTree *html.Node, tagName string, backtracking bool) {
+func deleteByLinkDensity(subTree *html.Node, tagName string, backtracking bool, opts Options) {
 	var nodesToDelete []*html.Node
 	textNodes := make(map[string][]*html.Node)
 
 	for _, elem := range etree.Iter(subTree, tagName) {
-		nonEmptyLinks, isHighDensity := linkDensityTest(elem)
+		nonEmptyLinks, isHighDensity := linkDensityTest(elem, opts)
 
 		if isHighDensity {
 			nodesToDelete = append(nodesToDelete, elem)
@@ -420,7 +606,7 @@ func deleteByLinkDensity(subTree *html.Node, tagName string, backtracking bool)
 
 	if backtracking {
 		for text, nodes := range textNodes {
-			textLength := utf8.RuneCountInString(text)
+			textLength := weightedLength(text, opts.Config)
 			if textLength > 0 && textLength < 1000 && len(nodes) >= 3 {
 				nodesToDelete = append(nodesToDelete, nodes...)
 			}
@@ -437,7 +623,11 @@ func handleTextElem(element *html.Node, potentialTags map[string]struct{}, cache
 	switch dom.TagName(element) {
 	case "ul", "ol", "dl":
 		return handleLists(element, cache, opts)
-	case "blockquote", "pre", "q", "code":
+	case "pre":
+		return handleCodeBlock(element, cache, opts)
+	case "code":
+		return handleCode(element, cache, opts)
+	case "blockquote", "q":
 		return handleQuotes(element, cache, opts)
 	case "h1", "h2", "h3", "h4", "h5", "h6", "summary":
 		return handleTitles(element, cache, opts)
@@ -469,39 +659,33 @@ func handleTextElem(element *html.Node, potentialTags map[string]struct{}, cache
 	return nil
 }
 
-// handleLists process lists elements
+// handleLists process lists elements, including the nested lists within its items.
 func handleLists(element *html.Node, cache *lru.Cache, opts Options) *html.Node {
 	processedElement := etree.Element(dom.TagName(element))
+	copyListAttributes(element, processedElement)
 
-	if text := etree.Text(element); text != "" {
+	if text := trim(etree.Text(element)); text != "" {
 		etree.SetText(processedElement, text)
 	}
 
 	for _, child := range etree.Iter(element, "dd", "dt", "li") {
+		// Skip item that already processed as part of nested list
+		if dom.TagName(child) == "done" {
+			continue
+		}
+
 		newChild := dom.CreateElement(dom.TagName(child))
+		copyListAttributes(child, newChild)
 
 		if len(dom.Children(child)) == 0 {
 			processedChild := processNode(child, cache, opts)
 			if processedChild != nil {
 				etree.SetText(newChild, etree.Text(processedChild))
 				etree.SetTail(newChild, etree.Tail(processedChild))
-				etree.Append(processedElement, newChild)
 			}
 		} else {
-			for _, subElement := range etree.Iter(child) {
-				processedSubChild := handleTextNode(subElement, cache, false, opts)
-				if processedSubChild != nil {
-					subChildElement := etree.SubElement(newChild, dom.TagName(processedSubChild))
-					etree.SetText(subChildElement, etree.Text(processedSubChild))
-					etree.SetTail(subChildElement, etree.Tail(processedSubChild))
-				}
-
-				if subElement.Type == html.ElementNode {
-					subElement.Data = "done"
-				}
-			}
-
-			etree.StripTags(newChild, "dd", "dt", "li")
+			processListItem(child, newChild, cache, opts)
+			trimEdges(newChild)
 		}
 
 		if etree.Text(newChild) != "" || len(dom.Children(newChild)) > 0 {
@@ -519,11 +703,132 @@ func handleLists(element *html.Node, cache *lru.Cache, opts Options) *html.Node
 	return nil
 }
 
+// processListItem copies the content of list item into the new item while keeping
+// its structure, e.g. nested lists, paragraphs, code and links. The descendants
+// of the item are marked as done, so they won't be processed again.
+func processListItem(item, newItem *html.Node, cache *lru.Cache, opts Options) {
+	appendText(newItem, etree.Text(item))
+
+	for _, child := range dom.Children(item) {
+		tagName := dom.TagName(child)
+		_, isFormatting := formatTagCatalog[tagName]
+		_, isKept := listItemTagCatalog[tagName]
+
+		switch {
+		case tagName == "done":
+		case tagName == "ul" || tagName == "ol" || tagName == "dl":
+			if processedChild := handleLists(child, cache, opts); processedChild != nil {
+				etree.Append(newItem, processedChild)
+			}
+		case tagName == "pre" || (tagName == "code" && isCodeBlock(child)):
+			if processedChild := handleCodeBlock(child, cache, opts); processedChild != nil {
+				etree.SetTail(processedChild, "")
+				etree.Append(newItem, processedChild)
+			}
+		case tagName == "br":
+			// Only keep line break that followed by text
+			next := dom.NextElementSibling(child)
+			if trim(etree.Tail(child)) != "" ||
+				(next != nil && dom.TagName(next) != "br" && trim(dom.TextContent(next)) != "") {
+				etree.SubElement(newItem, tagName)
+			}
+		case len(dom.Children(child)) == 0:
+			text := collapseSpace(etree.Text(child))
+			if !rxWords.MatchString(text) || textFilter(child, opts) ||
+				(opts.Deduplicate && cache != nil && duplicateTest(child, cache, opts)) {
+				break
+			}
+
+			if isFormatting || isKept {
+				processedChild := etree.SubElement(newItem, tagName)
+				copyLinkAttributes(child, processedChild)
+				etree.SetText(processedChild, trim(text))
+			} else {
+				// Unwrap the leaf container, e.g. <span> that only has text
+				appendText(newItem, text)
+			}
+		case isFormatting || isKept:
+			processedChild := dom.CreateElement(tagName)
+			copyLinkAttributes(child, processedChild)
+			processListItem(child, processedChild, cache, opts)
+			trimEdges(processedChild)
+			if etree.Text(processedChild) != "" || len(dom.Children(processedChild)) > 0 {
+				etree.Append(newItem, processedChild)
+			}
+		default:
+			// Unwrap the container, e.g. <div> or <span>
+			processListItem(child, newItem, cache, opts)
+		}
+
+		child.Data = "done"
+		appendText(newItem, etree.Tail(child))
+	}
+}
+
+// appendText adds the text at the end of element, i.e. after its last child.
+func appendText(element *html.Node, text string) {
+	if text = collapseSpace(text); text == "" {
+		return
+	}
+
+	children := dom.Children(element)
+	if len(children) == 0 {
+		etree.SetText(element, collapseSpace(etree.Text(element)+text))
+		return
+	}
+
+	lastChild := children[len(children)-1]
+	etree.SetTail(lastChild, collapseSpace(etree.Tail(lastChild)+text))
+}
+
+// trimEdges removes the spaces at the start and the end of element content.
+func trimEdges(element *html.Node) {
+	children := dom.Children(element)
+	if len(children) == 0 {
+		etree.SetText(element, strings.TrimSpace(etree.Text(element)))
+		return
+	}
+
+	etree.SetText(element, strings.TrimLeft(etree.Text(element), " "))
+	lastChild := children[len(children)-1]
+	etree.SetTail(lastChild, strings.TrimRight(etree.Tail(lastChild), " "))
+}
+
+// copyListAttributes copies the attributes that define the numbering of list items.
+func copyListAttributes(src, dst *html.Node) {
+	for _, name := range []string{"start", "type", "reversed", "value"} {
+		if dom.HasAttribute(src, name) {
+			dom.SetAttribute(dst, name, trim(dom.GetAttribute(src, name)))
+		}
+	}
+}
+
+// copyLinkAttributes copies the target of link element.
+func copyLinkAttributes(src, dst *html.Node) {
+	if dom.TagName(src) != "a" {
+		return
+	}
+
+	for _, name := range []string{"href", "target"} {
+		if value := trim(dom.GetAttribute(src, name)); value != "" {
+			dom.SetAttribute(dst, name, value)
+		}
+	}
+}
+
 // handleQuotes process quotes elements.
 func handleQuotes(element *html.Node, cache *lru.Cache, opts Options) *html.Node {
 	processedElement := etree.Element(dom.TagName(element))
 
 	for _, child := range etree.Iter(element) {
+		// Keep the code block inside quote as it is
+		if dom.TagName(child) == "pre" {
+			if processedChild := handleCodeBlock(child, cache, opts); processedChild != nil {
+				etree.Append(processedElement, processedChild)
+			}
+			continue
+		}
+
 		processedChild := processNode(child, cache, opts)
 		if processedChild != nil {
 			newSub := etree.SubElement(processedElement, dom.TagName(child))
@@ -534,7 +839,7 @@ func handleQuotes(element *html.Node, cache *lru.Cache, opts Options) *html.Node
 	}
 
 	if len(dom.Children(processedElement)) > 0 && textCharsTest(etree.IterText(processedElement, "")) {
-		etree.StripTags(processedElement, "blockquote", "pre", "q")
+		etree.StripTags(processedElement, "blockquote", "q")
 		return processedElement
 	}
 
@@ -653,7 +958,10 @@ func handleParagraphs(element *html.Node, potentialTags map[string]struct{}, cac
 		return processedElement
 	}
 
-	logWarn(opts, "discarding p-child: %s", trim(etree.ToString(processedElement)))
+	// Only stringify the element when it will be logged, since it clones the element
+	if opts.EnableLog {
+		logWarn(opts, "discarding p-child: %s", trim(etree.ToString(processedElement)))
+	}
 	return nil
 }
 
@@ -829,8 +1137,9 @@ func compareExtraction(doc, originalExtract *html.Node, opts Options) (*html.Nod
 	// Prepare fallback candidates
 	fallbackCandidates := opts.FallbackCandidates
 
-	// If fallback candidates are empty, populate it first
-	if len(fallbackCandidates) == 0 {
+	// If fallback candidates are empty, populate it first. The external extractors
+	// are skipped if time budget already runs out.
+	if len(fallbackCandidates) == 0 && !deadlineExceeded(opts) {
 		fallbackCandidates = []*html.Node{}
 
 		readabilityExtract, err := tryReadability(doc, opts)
@@ -855,11 +1164,23 @@ func compareExtraction(doc, originalExtract *html.Node, opts Options) (*html.Nod
 
 	// Compare
 	originalText := trim(etree.IterText(originalExtract, " "))
-	lenOriginal := utf8.RuneCountInString(originalText)
+	lenOriginal := weightedLength(originalText, opts.Config)
+	var usedFallback FallbackExtractor
 
 	for i, candidate := range fallbackCandidates {
+		// Find the name of the candidate for metrics
+		fallbackName := FallbackCandidate
+		if len(opts.FallbackCandidates) == 0 {
+			fallbackName = FallbackReadability
+		}
+
 		// Use dom-distiller if necessary
 		if candidate == nil {
+			if deadlineExceeded(opts) {
+				break
+			}
+
+			fallbackName = FallbackDomDistiller
 			var err error
 			candidate, err = tryDomDistiller(doc, opts)
 			if err != nil {
@@ -870,7 +1191,7 @@ func compareExtraction(doc, originalExtract *html.Node, opts Options) (*html.Nod
 
 		// Extract text from candidate
 		candidateText := trim(etree.IterText(candidate, " "))
-		lenCandidate := utf8.RuneCountInString(candidateText)
+		lenCandidate := weightedLength(candidateText, opts.Config)
 		logInfo(opts, "extracted length: %d (candidate-%d) %d (original)", lenCandidate, i+1, lenOriginal)
 
 		// Check if this candidate can be used
@@ -881,6 +1202,7 @@ func compareExtraction(doc, originalExtract *html.Node, opts Options) (*html.Nod
 			originalExtract = candidate
 			originalText = candidateText
 			lenOriginal = lenCandidate
+			usedFallback = fallbackName
 			logInfo(opts, "candidate-%d usable: %s", i+1, originalUrl)
 		}
 
@@ -890,11 +1212,15 @@ func compareExtraction(doc, originalExtract *html.Node, opts Options) (*html.Nod
 		}
 	}
 
+	if usedFallback != "" {
+		observeFallback(opts, usedFallback)
+	}
+
 	// Sanitize the tree
 	sanitizeTree(originalExtract, opts)
 
 	// Return data
-	finalText := trim(etree.IterText(originalExtract, " "))
+	finalText := contentText(originalExtract)
 	return originalExtract, finalText
 }
 
@@ -0,0 +1,141 @@
+// This file is part of go-trafilatura, Go package for extracting readable
+// content, comments and metadata from a web page. Source available in
+// <https://github.com/markusmobius/go-trafilatura>.
+// Copyright (C) 2021 Markus Mobius
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or (at your
+// option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+// or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License
+// for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package trafilatura
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gogs/chardet"
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// maxMetaCharsetScan is the number of bytes from the start of document
+// that will be scanned while looking for <meta> charset declaration.
+const maxMetaCharsetScan = 4096
+
+var rxMetaCharset = regexp.MustCompile(`(?i)<meta[^>]+charset\s*=\s*["']?\s*([a-z0-9_:.\-]+)`)
+
+var byteOrderMarks = []struct {
+	bom     []byte
+	charset string
+}{
+	{[]byte{0xEF, 0xBB, 0xBF}, "utf-8"},
+	{[]byte{0xFE, 0xFF}, "utf-16be"},
+	{[]byte{0xFF, 0xFE}, "utf-16le"},
+}
+
+// detectEncoding looks for the character encoding of a HTML document. The encoding is
+// determined in following order: byte order mark, charset in Content-Type header,
+// charset in <meta> tags and finally by sniffing the content itself. If everything
+// failed, the document is assumed to be UTF-8.
+func detectEncoding(content []byte, contentType string) (encoding.Encoding, string) {
+	// Check byte order mark
+	for _, item := range byteOrderMarks {
+		if bytes.HasPrefix(content, item.bom) {
+			if enc, name := charset.Lookup(item.charset); enc != nil {
+				return enc, name
+			}
+		}
+	}
+
+	// Check HTTP header
+	if _, params, err := mime.ParseMediaType(contentType); err == nil {
+		if enc, name := charset.Lookup(params["charset"]); enc != nil {
+			return enc, name
+		}
+	}
+
+	// Check <meta charset> and <meta http-equiv="Content-Type">
+	head := content
+	if len(head) > maxMetaCharsetScan {
+		head = head[:maxMetaCharsetScan]
+	}
+
+	if matches := rxMetaCharset.FindSubmatch(head); len(matches) > 1 {
+		enc, name := charset.Lookup(string(matches[1]))
+
+		// Per HTML spec, UTF-16 declared in <meta> is treated as UTF-8 since
+		// the declaration itself is only readable in ASCII compatible encoding.
+		if strings.HasPrefix(name, "utf-16") {
+			enc, name = charset.Lookup("utf-8")
+		}
+
+		// Some pages declare UTF-8 while actually using something else,
+		// so only trust it if the content is really valid UTF-8.
+		if enc != nil && (name != "utf-8" || utf8.Valid(content)) {
+			return enc, name
+		}
+	}
+
+	// Sniff the content
+	if utf8.Valid(content) {
+		enc, name := charset.Lookup("utf-8")
+		return enc, name
+	}
+
+	result, err := chardet.NewHtmlDetector().DetectBest(content)
+	if err == nil {
+		if enc, name := charset.Lookup(result.Charset); enc != nil {
+			return enc, name
+		}
+	}
+
+	enc, name := charset.Lookup("utf-8")
+	return enc, name
+}
+
+// decodeHTML reads the HTML document from reader and convert it into UTF-8. It returns
+// function that creates reader for the converted document, which can be called several
+// times to read the document again, along with name of the original encoding.
+func decodeHTML(r io.Reader, contentType string) (func() io.Reader, string, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, "", err
+	}
+
+	enc, name := detectEncoding(content, contentType)
+	for _, item := range byteOrderMarks {
+		content = bytes.TrimPrefix(content, item.bom)
+	}
+
+	newReader := func() io.Reader {
+		var decoded io.Reader = bytes.NewReader(content)
+		decoded = transform.NewReader(decoded, enc.NewDecoder())
+		return normalizeText(decoded)
+	}
+
+	return newReader, name, nil
+}
+
+// normalizeText converts text from NFD to NFC and remove soft hyphens,
+// the same way as done by `dom.Parse`.
+func normalizeText(r io.Reader) io.Reader {
+	softHyphen := runes.Predicate(func(r rune) bool { return r == '\u00AD' })
+	transformer := transform.Chain(norm.NFD, runes.Remove(softHyphen), norm.NFC)
+	return transform.NewReader(r, transformer)
+}
@@ -0,0 +1,91 @@
+// This file is part of go-trafilatura, Go package for extracting readable
+// content, comments and metadata from a web page. Source available in
+// <https://github.com/markusmobius/go-trafilatura>.
+// Copyright (C) 2021 Markus Mobius
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or (at your
+// option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+// or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License
+// for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package trafilatura
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+func Test_DetectEncoding(t *testing.T) {
+	encode := func(enc encoding.Encoding, str string) []byte {
+		result, err := enc.NewEncoder().Bytes([]byte(str))
+		assert.Nil(t, err)
+		return result
+	}
+
+	// Byte order mark
+	content := append([]byte{0xEF, 0xBB, 0xBF}, []byte("<html><body>Test</body></html>")...)
+	_, name := detectEncoding(content, "text/html; charset=windows-1252")
+	assert.Equal(t, "utf-8", name)
+
+	// HTTP header
+	content = encode(charmap.Windows1251, "<html><body><p>Привет, мир</p></body></html>")
+	_, name = detectEncoding(content, "text/html; charset=windows-1251")
+	assert.Equal(t, "windows-1251", name)
+
+	// Meta charset
+	content = encode(japanese.ShiftJIS, `<html><head><meta charset="Shift_JIS"></head><body><p>日本語</p></body></html>`)
+	_, name = detectEncoding(content, "text/html")
+	assert.Equal(t, "shift_jis", name)
+
+	// Meta http-equiv
+	content = encode(simplifiedchinese.GBK, `<html><head><meta http-equiv="Content-Type" content="text/html; charset=gbk"></head><body><p>中文</p></body></html>`)
+	_, name = detectEncoding(content, "")
+	assert.Equal(t, "gbk", name)
+
+	// Meta declares UTF-16, which should be treated as UTF-8
+	content = []byte(`<html><head><meta charset="utf-16"></head><body><p>Test</p></body></html>`)
+	_, name = detectEncoding(content, "")
+	assert.Equal(t, "utf-8", name)
+
+	// Valid UTF-8 without any declaration
+	content = []byte("<html><body><p>Ünïcödé</p></body></html>")
+	_, name = detectEncoding(content, "")
+	assert.Equal(t, "utf-8", name)
+}
+
+func Test_ExtractEncoding(t *testing.T) {
+	text := "Съешь же ещё этих мягких французских булок, да выпей чаю. " +
+		"Широкая электрификация южных губерний даст мощный толчок подъёму сельского хозяйства."
+	rawHTML := `<html><head><title>Тест</title></head><body><article><p>` + text + `</p></article></body></html>`
+
+	content, err := charmap.Windows1251.NewEncoder().Bytes([]byte(rawHTML))
+	assert.Nil(t, err)
+
+	// Without header, encoding should be sniffed from content
+	result, err := Extract(bytes.NewReader(content), zeroOpts)
+	assert.Nil(t, err)
+	assert.Equal(t, "windows-1251", result.Encoding)
+	assert.Contains(t, result.ContentText, "мягких французских булок")
+
+	// With header
+	opts := zeroOpts
+	opts.ContentType = "text/html; charset=windows-1251"
+	result, err = Extract(bytes.NewReader(content), opts)
+	assert.Nil(t, err)
+	assert.Equal(t, "windows-1251", result.Encoding)
+	assert.Contains(t, result.ContentText, "мягких французских булок")
+}
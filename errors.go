@@ -0,0 +1,85 @@
+// This file is part of go-trafilatura, Go package for extracting readable
+// content, comments and metadata from a web page. Source available in
+// <https://github.com/markusmobius/go-trafilatura>.
+// Copyright (C) 2021 Markus Mobius
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or (at your
+// option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+// or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License
+// for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package trafilatura
+
+import (
+	"fmt"
+	"runtime/debug"
+	"time"
+)
+
+// PanicError is returned when the extraction panics, e.g. because of a bug that
+// triggered by a malformed document. It's recovered so a single bad page won't
+// crash the whole program.
+type PanicError struct {
+	// Value is the value that passed to panic.
+	Value interface{}
+
+	// Stack is the stack trace of goroutine when the panic happened.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("extraction panicked: %v", e.Value)
+}
+
+// recoverPanic recovers the panic in extraction and returns it as PanicError. It must
+// be deferred directly by the function that returns the result and error.
+func recoverPanic(opts Options, result **ExtractResult, err *error) {
+	if r := recover(); r != nil {
+		observeOutcome(opts, OutcomePanic)
+		*result, *err = nil, &PanicError{Value: r, Stack: debug.Stack()}
+	}
+}
+
+// LimitKind is the kind of resource limit that specified in Options.
+type LimitKind string
+
+const (
+	LimitInputBytes LimitKind = "input_bytes"
+	LimitNodes      LimitKind = "nodes"
+	LimitDepth      LimitKind = "depth"
+	LimitDuration   LimitKind = "duration"
+)
+
+// LimitError is returned when the document or its extraction exceeds one
+// of the resource limits in Options.
+type LimitError struct {
+	// Kind is the kind of the exceeded limit.
+	Kind LimitKind
+
+	// Limit is the value of the exceeded limit. For LimitDuration,
+	// it's the duration in nanoseconds.
+	Limit int64
+}
+
+func (e *LimitError) Error() string {
+	switch e.Kind {
+	case LimitInputBytes:
+		return fmt.Sprintf("input is larger than %d bytes", e.Limit)
+	case LimitNodes:
+		return fmt.Sprintf("document has more than %d nodes", e.Limit)
+	case LimitDepth:
+		return fmt.Sprintf("document is nested deeper than %d levels", e.Limit)
+	case LimitDuration:
+		return fmt.Sprintf("extraction exceeded time budget of %v", time.Duration(e.Limit))
+	default:
+		return fmt.Sprintf("%s exceeded limit %d", e.Kind, e.Limit)
+	}
+}
@@ -0,0 +1,233 @@
+// This file is part of go-trafilatura, Go package for extracting readable
+// content, comments and metadata from a web page. Source available in
+// <https://github.com/markusmobius/go-trafilatura>.
+// Copyright (C) 2021 Markus Mobius
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or (at your
+// option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+// or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License
+// for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program. If not, see <https://www.gnu.org/licenses/>.
+
+// Package eval evaluates the extraction result of Trafilatura and other extractors
+// against a gold set, i.e. list of documents with text snippets that must and must
+// not be found in the extraction result.
+package eval
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	nurl "net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-shiori/dom"
+	"github.com/go-shiori/go-readability"
+	distiller "github.com/markusmobius/go-domdistiller"
+	"github.com/markusmobius/go-trafilatura"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+)
+
+// Mode is the extractor that evaluated.
+type Mode string
+
+const (
+	// ModeNothing is null hypothesis where the extraction result is empty.
+	ModeNothing Mode = "nothing"
+
+	// ModeEverything is null hypothesis where the extraction result is the entire HTML.
+	ModeEverything Mode = "everything"
+
+	// ModeTrafilatura is Trafilatura without fallback extractors.
+	ModeTrafilatura Mode = "trafilatura"
+
+	// ModeFallback is Trafilatura with readability and dom-distiller as fallback.
+	ModeFallback Mode = "fallback"
+
+	// ModeReadability is go-readability.
+	ModeReadability Mode = "readability"
+
+	// ModeDomDistiller is go-domdistiller.
+	ModeDomDistiller Mode = "dom-distiller"
+)
+
+// AllModes is list of all available modes.
+var AllModes = []Mode{ModeNothing, ModeEverything, ModeTrafilatura, ModeFallback, ModeReadability, ModeDomDistiller}
+
+// Config is the configuration for evaluation.
+type Config struct {
+	// Modes is the list of extractors to evaluate. If empty, all modes are evaluated.
+	Modes []Mode
+
+	// Options is the base options for Trafilatura. `OriginalURL`, `NoFallback` and
+	// `FallbackCandidates` are set by evaluator depending on the mode.
+	Options trafilatura.Options
+
+	// HttpClient is used to download document that doesn't have file.
+	// If it's nil, the default HTTP client is used.
+	HttpClient *http.Client
+}
+
+// Evaluate runs the extractors on every document in gold set and measure their performance.
+func Evaluate(entries []GoldEntry, cfg Config) (*Report, error) {
+	modes := cfg.Modes
+	if len(modes) == 0 {
+		modes = AllModes
+	}
+
+	for _, mode := range modes {
+		if !isValidMode(mode) {
+			return nil, fmt.Errorf("unknown mode: %s", mode)
+		}
+	}
+
+	if cfg.HttpClient == nil {
+		cfg.HttpClient = http.DefaultClient
+	}
+
+	report := newReport(modes)
+	for _, entry := range entries {
+		content, err := loadDocument(entry, cfg.HttpClient)
+		if err != nil {
+			report.addFailedDocument(entry, err)
+			continue
+		}
+
+		var url *nurl.URL
+		if entry.URL != "" {
+			url, _ = nurl.ParseRequestURI(entry.URL)
+		}
+
+		report.Documents++
+		for _, mode := range modes {
+			start := time.Now()
+			result, err := runMode(mode, url, content, cfg.Options)
+			duration := time.Since(start)
+			report.addResult(mode, entry, result, duration, err)
+		}
+	}
+
+	return report, nil
+}
+
+func isValidMode(mode Mode) bool {
+	for _, m := range AllModes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// loadDocument reads the document from file, or downloads it if file is not specified.
+func loadDocument(entry GoldEntry, client *http.Client) ([]byte, error) {
+	if entry.File != "" {
+		return os.ReadFile(entry.File)
+	}
+
+	resp, err := client.Get(entry.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("server returns status %d", resp.StatusCode)
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+// runMode extracts the text content of document using the specified mode.
+func runMode(mode Mode, url *nurl.URL, content []byte, opts trafilatura.Options) (string, error) {
+	switch mode {
+	case ModeNothing:
+		return "", nil
+	case ModeEverything:
+		return string(content), nil
+	}
+
+	// Trafilatura detects the character encoding by itself
+	if mode == ModeTrafilatura || mode == ModeFallback {
+		opts.OriginalURL = url
+		opts.NoFallback = mode == ModeTrafilatura
+		opts.FallbackCandidates = nil
+		return runTrafilatura(content, opts)
+	}
+
+	// For other extractors, convert the document into UTF-8 before parsing it
+	reader, err := charset.NewReader(bytes.NewReader(content), "")
+	if err != nil {
+		return "", err
+	}
+
+	doc, err := dom.Parse(reader)
+	if err != nil {
+		return "", err
+	}
+
+	switch mode {
+	case ModeReadability:
+		return runReadability(url, doc)
+	default:
+		return runDomDistiller(url, doc)
+	}
+}
+
+func runTrafilatura(content []byte, opts trafilatura.Options) (string, error) {
+	result, err := trafilatura.Extract(bytes.NewReader(content), opts)
+	if err != nil {
+		return "", err
+	}
+
+	return result.ContentText, nil
+}
+
+func runReadability(url *nurl.URL, doc *html.Node) (string, error) {
+	article, err := readability.FromDocument(doc, url)
+	if err != nil {
+		return "", err
+	}
+
+	return article.TextContent, nil
+}
+
+func runDomDistiller(url *nurl.URL, doc *html.Node) (string, error) {
+	res, err := distiller.Apply(doc, &distiller.Options{
+		OriginalURL:    url,
+		SkipPagination: true})
+	if err != nil {
+		return "", err
+	}
+
+	return res.Text, nil
+}
+
+// compareSnippets checks the snippets in extraction result, and returns the
+// must-contain snippets that missing and must-not-contain snippets that found.
+func compareSnippets(result string, entry GoldEntry) (missing []string, unwanted []string) {
+	for _, snippet := range entry.MustContain {
+		if result == "" || !strings.Contains(result, snippet) {
+			missing = append(missing, snippet)
+		}
+	}
+
+	for _, snippet := range entry.MustNotContain {
+		if result != "" && strings.Contains(result, snippet) {
+			unwanted = append(unwanted, snippet)
+		}
+	}
+
+	return
+}
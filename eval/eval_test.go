@@ -0,0 +1,140 @@
+// This file is part of go-trafilatura, Go package for extracting readable
+// content, comments and metadata from a web page. Source available in
+// <https://github.com/markusmobius/go-trafilatura>.
+// Copyright (C) 2021 Markus Mobius
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or (at your
+// option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+// or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License
+// for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package eval
+
+import (
+	"os"
+	fp "path/filepath"
+	"testing"
+
+	"github.com/markusmobius/go-trafilatura"
+	"github.com/stretchr/testify/assert"
+)
+
+var testGoldSet = []GoldEntry{{
+	URL:  "https://www.adac.de/rund-ums-fahrzeug/tests/kindersicherheit/kindersitztest-2018/",
+	File: "../test-files/mock/adac.de.kindersitze.html",
+	MustContain: []string{
+		"Elf Modelle sind empfehlenswert",
+		"in punkto Sicherheit, Bedienung, Ergonomie",
+	},
+	MustNotContain: []string{
+		"23.10.2018",
+		"Rund ums Fahrzeug",
+	},
+}}
+
+func Test_GoldSet(t *testing.T) {
+	tmpDir := t.TempDir()
+	absFile, _ := fp.Abs(testGoldSet[0].File)
+
+	for _, name := range []string{"gold.json", "gold.yaml"} {
+		path := fp.Join(tmpDir, name)
+		err := SaveGoldSet(path, testGoldSet)
+		assert.Nil(t, err)
+
+		entries, err := LoadGoldSet(path)
+		assert.Nil(t, err)
+		assert.Len(t, entries, 1)
+		assert.Equal(t, testGoldSet[0].URL, entries[0].URL)
+		assert.Equal(t, absFile, entries[0].File)
+		assert.Equal(t, testGoldSet[0].MustContain, entries[0].MustContain)
+		assert.Equal(t, testGoldSet[0].MustNotContain, entries[0].MustNotContain)
+	}
+
+	// Unknown format and invalid entry
+	_, err := LoadGoldSet("gold.txt")
+	assert.NotNil(t, err)
+
+	path := fp.Join(tmpDir, "invalid.json")
+	os.WriteFile(path, []byte(`[{"mustContain": ["a"]}]`), 0644)
+	_, err = LoadGoldSet(path)
+	assert.NotNil(t, err)
+}
+
+func Test_Evaluate(t *testing.T) {
+	entries := append(testGoldSet, GoldEntry{File: "not-exist.html"})
+	report, err := Evaluate(entries, Config{
+		Modes: []Mode{ModeNothing, ModeEverything, ModeTrafilatura},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, report.Documents)
+	assert.Len(t, report.FailedDocuments, 1)
+	assert.Len(t, report.Modes, 3)
+
+	// Extracting nothing means every wanted snippet is missing
+	nothing := report.Mode(ModeNothing).Score
+	assert.Equal(t, 0, nothing.TruePositives)
+	assert.Equal(t, 2, nothing.FalseNegatives)
+	assert.Equal(t, 2, nothing.TrueNegatives)
+
+	// Extracting everything means every unwanted snippet is found
+	everything := report.Mode(ModeEverything).Score
+	assert.Equal(t, 2, everything.TruePositives)
+	assert.Equal(t, 2, everything.FalsePositives)
+
+	trafilatura := report.Mode(ModeTrafilatura).Score
+	assert.Equal(t, 2, trafilatura.TruePositives)
+	assert.Equal(t, 1.0, trafilatura.Recall)
+	assert.Equal(t, 2, trafilatura.TrueNegatives+trafilatura.FalsePositives)
+
+	// Unknown mode
+	_, err = Evaluate(entries, Config{Modes: []Mode{"unknown"}})
+	assert.NotNil(t, err)
+}
+
+func Test_runModeEncoding(t *testing.T) {
+	// Document in ISO-8859-1, which must be decoded before extraction
+	paragraph := "<p>Le caf\xe9 est servi tr\xe8s chaud dans la grande salle du ch\xe2teau, " +
+		"o\xf9 les invit\xe9s attendent patiemment le d\xe9but de la c\xe9r\xe9monie.</p>"
+	content := []byte(`<html><head><meta charset="iso-8859-1"><title>Caf\xe9</title></head>` +
+		"<body><article>" + paragraph + paragraph + paragraph + "</article></body></html>")
+
+	for _, mode := range []Mode{ModeTrafilatura, ModeFallback, ModeReadability, ModeDomDistiller} {
+		result, err := runMode(mode, nil, content, trafilatura.Options{})
+		assert.Nil(t, err, mode)
+		assert.Contains(t, result, "Le café est servi très chaud", mode)
+	}
+}
+
+func Test_Compare(t *testing.T) {
+	baseline, err := Evaluate(testGoldSet, Config{Modes: []Mode{ModeEverything}})
+	assert.Nil(t, err)
+
+	// Same result, no regression
+	current, err := Evaluate(testGoldSet, Config{Modes: []Mode{ModeEverything}})
+	assert.Nil(t, err)
+	assert.Empty(t, current.Compare(baseline, 0.005))
+
+	// Pretend the mode used to be perfect
+	baseline.Modes[0].Score.F1 = 1
+	baseline.Modes[0].Documents[0].Unwanted = nil
+
+	regressions := current.Compare(baseline, 0.005)
+	assert.Len(t, regressions, 2)
+	assert.Equal(t, "f1", regressions[0].Metric)
+	assert.Equal(t, "unwanted", regressions[1].Metric)
+	assert.Equal(t, testGoldSet[0].URL, regressions[1].Document)
+	assert.Len(t, regressions[1].Snippets, 2)
+
+	// Drop within tolerance is not a regression
+	baseline.Modes[0].Documents[0].Unwanted = current.Modes[0].Documents[0].Unwanted
+	baseline.Modes[0].Score.F1 = current.Modes[0].Score.F1 + 0.001
+	assert.Empty(t, current.Compare(baseline, 0.005))
+}
@@ -0,0 +1,127 @@
+// This file is part of go-trafilatura, Go package for extracting readable
+// content, comments and metadata from a web page. Source available in
+// <https://github.com/markusmobius/go-trafilatura>.
+// Copyright (C) 2021 Markus Mobius
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or (at your
+// option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+// or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License
+// for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	fp "path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// GoldEntry is a single document in gold set, along with the snippets that must
+// and must not be found in the extraction result.
+type GoldEntry struct {
+	// URL is the original URL of the document. If File is empty, the document
+	// will be downloaded from this URL.
+	URL string `json:"url" yaml:"url"`
+
+	// File is the path to the HTML file. If it's relative, it's resolved
+	// against the directory of gold set file.
+	File string `json:"file,omitempty" yaml:"file,omitempty"`
+
+	// MustContain is the list of snippets that must exist in extraction result.
+	MustContain []string `json:"mustContain" yaml:"mustContain"`
+
+	// MustNotContain is the list of snippets that must not exist in extraction result.
+	MustNotContain []string `json:"mustNotContain" yaml:"mustNotContain"`
+}
+
+// LoadGoldSet loads the gold set from JSON or YAML file, which is chosen by its extension.
+// The relative file path in each entry is converted to be relative to the gold set file.
+func LoadGoldSet(path string) ([]GoldEntry, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []GoldEntry
+	switch strings.ToLower(fp.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &entries)
+	case ".json":
+		err = json.Unmarshal(content, &entries)
+	default:
+		return nil, fmt.Errorf("unknown gold set format: %s", path)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse gold set: %w", err)
+	}
+
+	baseDir := fp.Dir(path)
+	for i, entry := range entries {
+		if entry.URL == "" && entry.File == "" {
+			return nil, fmt.Errorf("entry %d doesn't have url nor file", i+1)
+		}
+
+		if entry.File != "" && !fp.IsAbs(entry.File) {
+			entries[i].File = fp.Join(baseDir, entry.File)
+		}
+	}
+
+	return entries, nil
+}
+
+// SaveGoldSet saves the gold set into JSON or YAML file, which is chosen by its extension.
+// The file path in each entry is converted to be relative to the gold set file, so it
+// can be loaded back using LoadGoldSet.
+func SaveGoldSet(path string, entries []GoldEntry) error {
+	baseDir, err := fp.Abs(fp.Dir(path))
+	if err != nil {
+		return err
+	}
+
+	entries = append([]GoldEntry{}, entries...)
+	for i, entry := range entries {
+		if entry.File == "" {
+			continue
+		}
+
+		absPath, err := fp.Abs(entry.File)
+		if err != nil {
+			return err
+		}
+
+		if relPath, err := fp.Rel(baseDir, absPath); err == nil {
+			entries[i].File = relPath
+		} else {
+			entries[i].File = absPath
+		}
+	}
+
+	var content []byte
+	switch strings.ToLower(fp.Ext(path)) {
+	case ".yaml", ".yml":
+		content, err = yaml.Marshal(entries)
+	case ".json":
+		content, err = json.MarshalIndent(entries, "", "  ")
+	default:
+		return fmt.Errorf("unknown gold set format: %s", path)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, content, 0644)
+}
@@ -0,0 +1,336 @@
+// This file is part of go-trafilatura, Go package for extracting readable
+// content, comments and metadata from a web page. Source available in
+// <https://github.com/markusmobius/go-trafilatura>.
+// Copyright (C) 2021 Markus Mobius
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or (at your
+// option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+// or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License
+// for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package eval
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Score is the confusion matrix of the snippets along with the metrics that calculated from it.
+// Must-contain snippet that found is true positive while the missing one is false negative.
+// Must-not-contain snippet that found is false positive while the missing one is true negative.
+type Score struct {
+	TruePositives  int     `json:"truePositives"`
+	FalseNegatives int     `json:"falseNegatives"`
+	FalsePositives int     `json:"falsePositives"`
+	TrueNegatives  int     `json:"trueNegatives"`
+	Precision      float64 `json:"precision"`
+	Recall         float64 `json:"recall"`
+	F1             float64 `json:"f1"`
+	Accuracy       float64 `json:"accuracy"`
+}
+
+func (s *Score) add(other Score) {
+	s.TruePositives += other.TruePositives
+	s.FalseNegatives += other.FalseNegatives
+	s.FalsePositives += other.FalsePositives
+	s.TrueNegatives += other.TrueNegatives
+	s.calculate()
+}
+
+func (s *Score) calculate() {
+	tp := float64(s.TruePositives)
+	fn := float64(s.FalseNegatives)
+	fp := float64(s.FalsePositives)
+	tn := float64(s.TrueNegatives)
+
+	s.Precision = safeDivide(tp, tp+fp)
+	s.Recall = safeDivide(tp, tp+fn)
+	s.Accuracy = safeDivide(tp+tn, tp+tn+fp+fn)
+	s.F1 = safeDivide(2*tp, 2*tp+fp+fn)
+}
+
+func safeDivide(a, b float64) float64 {
+	if b == 0 {
+		return 0
+	}
+	return a / b
+}
+
+// DocumentResult is the evaluation result of a single document.
+type DocumentResult struct {
+	URL      string   `json:"url,omitempty"`
+	File     string   `json:"file,omitempty"`
+	Score    Score    `json:"score"`
+	Missing  []string `json:"missing,omitempty"`
+	Unwanted []string `json:"unwanted,omitempty"`
+	Error    string   `json:"error,omitempty"`
+	Duration float64  `json:"duration"`
+}
+
+// key returns identifier of the document, used to compare documents between reports.
+func (dr DocumentResult) key() string {
+	if dr.URL != "" {
+		return dr.URL
+	}
+	return dr.File
+}
+
+// ModeReport is the evaluation result of a mode.
+type ModeReport struct {
+	Mode          Mode             `json:"mode"`
+	Score         Score            `json:"score"`
+	Errors        int              `json:"errors"`
+	Duration      float64          `json:"duration"`
+	DocsPerSecond float64          `json:"docsPerSecond"`
+	Documents     []DocumentResult `json:"documents"`
+}
+
+// FailedDocument is the document in gold set that can't be loaded.
+type FailedDocument struct {
+	URL   string `json:"url,omitempty"`
+	File  string `json:"file,omitempty"`
+	Error string `json:"error"`
+}
+
+func (fd FailedDocument) key() string {
+	if fd.File != "" {
+		return fd.File
+	}
+	return fd.URL
+}
+
+// Report is the result of evaluation.
+type Report struct {
+	Documents       int              `json:"documents"`
+	FailedDocuments []FailedDocument `json:"failedDocuments,omitempty"`
+	Modes           []*ModeReport    `json:"modes"`
+}
+
+func newReport(modes []Mode) *Report {
+	report := &Report{}
+	for _, mode := range modes {
+		report.Modes = append(report.Modes, &ModeReport{Mode: mode})
+	}
+	return report
+}
+
+func (r *Report) addFailedDocument(entry GoldEntry, err error) {
+	r.FailedDocuments = append(r.FailedDocuments, FailedDocument{
+		URL:   entry.URL,
+		File:  entry.File,
+		Error: err.Error(),
+	})
+}
+
+func (r *Report) addResult(mode Mode, entry GoldEntry, result string, duration time.Duration, err error) {
+	// Extraction error is treated as empty result
+	missing, unwanted := compareSnippets(result, entry)
+	docResult := DocumentResult{
+		URL:      entry.URL,
+		File:     entry.File,
+		Missing:  missing,
+		Unwanted: unwanted,
+		Duration: duration.Seconds(),
+		Score: Score{
+			TruePositives:  len(entry.MustContain) - len(missing),
+			FalseNegatives: len(missing),
+			FalsePositives: len(unwanted),
+			TrueNegatives:  len(entry.MustNotContain) - len(unwanted),
+		},
+	}
+	docResult.Score.calculate()
+
+	modeReport := r.Mode(mode)
+	if err != nil {
+		docResult.Error = err.Error()
+		modeReport.Errors++
+	}
+
+	modeReport.Documents = append(modeReport.Documents, docResult)
+	modeReport.Score.add(docResult.Score)
+	modeReport.Duration += duration.Seconds()
+	modeReport.DocsPerSecond = safeDivide(float64(len(modeReport.Documents)), modeReport.Duration)
+}
+
+// Mode returns the report for the specified mode. Returns nil if the mode is not evaluated.
+func (r *Report) Mode(mode Mode) *ModeReport {
+	for _, modeReport := range r.Modes {
+		if modeReport.Mode == mode {
+			return modeReport
+		}
+	}
+	return nil
+}
+
+// WriteJSON writes the report in JSON format.
+func (r *Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// WriteText writes summary of the report as human readable text. If withDiffs is
+// true, the snippets that missing or unwanted for each document are printed as well.
+func (r *Report) WriteText(w io.Writer, withDiffs bool) error {
+	buffer := bufio.NewWriter(w)
+	fmt.Fprintf(buffer, "N Documents: %d\n", r.Documents)
+	for _, failed := range r.FailedDocuments {
+		fmt.Fprintf(buffer, "  failed to load %s: %s\n", failed.key(), failed.Error)
+	}
+
+	for _, mr := range r.Modes {
+		s := mr.Score
+		fmt.Fprintf(buffer, "\n%s: TP=%d FN=%d FP=%d TN=%d errors=%d duration=%.3f s (%.1f docs/s)\n",
+			mr.Mode, s.TruePositives, s.FalseNegatives, s.FalsePositives, s.TrueNegatives,
+			mr.Errors, mr.Duration, mr.DocsPerSecond)
+		fmt.Fprintf(buffer, "\tprecision=%.3f recall=%.3f acc=%.3f f-score=%.3f\n",
+			s.Precision, s.Recall, s.Accuracy, s.F1)
+
+		if !withDiffs {
+			continue
+		}
+
+		for _, doc := range mr.Documents {
+			if len(doc.Missing) == 0 && len(doc.Unwanted) == 0 && doc.Error == "" {
+				continue
+			}
+
+			fmt.Fprintf(buffer, "\t%s\n", doc.key())
+			if doc.Error != "" {
+				fmt.Fprintf(buffer, "\t\t! %s\n", doc.Error)
+			}
+
+			for _, snippet := range doc.Missing {
+				fmt.Fprintf(buffer, "\t\t- %q\n", snippet)
+			}
+
+			for _, snippet := range doc.Unwanted {
+				fmt.Fprintf(buffer, "\t\t+ %q\n", snippet)
+			}
+		}
+	}
+
+	return buffer.Flush()
+}
+
+// LoadReport loads the report that previously saved in JSON format.
+func LoadReport(path string) (*Report, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var report Report
+	if err := json.NewDecoder(f).Decode(&report); err != nil {
+		return nil, err
+	}
+
+	return &report, nil
+}
+
+// Regression is a metric that become worse compared to the baseline report.
+type Regression struct {
+	Mode     Mode     `json:"mode"`
+	Document string   `json:"document,omitempty"`
+	Metric   string   `json:"metric"`
+	Baseline float64  `json:"baseline"`
+	Current  float64  `json:"current"`
+	Snippets []string `json:"snippets,omitempty"`
+}
+
+func (rg Regression) String() string {
+	str := string(rg.Mode)
+	if rg.Document != "" {
+		str += " " + rg.Document
+	}
+	str = fmt.Sprintf("%s: %s %.4g => %.4g", str, rg.Metric, rg.Baseline, rg.Current)
+	for _, snippet := range rg.Snippets {
+		str += fmt.Sprintf("\n\t%q", snippet)
+	}
+	return str
+}
+
+// Compare compares the report with the baseline and returns the regressions. The score of a
+// mode is regressed if it drops more than tolerance, while a document is regressed if it has
+// more missing or unwanted snippets than before. Only modes that exist in both reports are compared.
+func (r *Report) Compare(baseline *Report, tolerance float64) []Regression {
+	var regressions []Regression
+	for _, current := range r.Modes {
+		old := baseline.Mode(current.Mode)
+		if old == nil {
+			continue
+		}
+
+		// Compare the scores
+		metrics := []struct {
+			name     string
+			old, new float64
+		}{
+			{"precision", old.Score.Precision, current.Score.Precision},
+			{"recall", old.Score.Recall, current.Score.Recall},
+			{"f1", old.Score.F1, current.Score.F1},
+			{"accuracy", old.Score.Accuracy, current.Score.Accuracy},
+		}
+
+		for _, metric := range metrics {
+			if old, new := metric.old, metric.new; old-new > tolerance {
+				regressions = append(regressions, Regression{
+					Mode:     current.Mode,
+					Metric:   metric.name,
+					Baseline: old,
+					Current:  new,
+				})
+			}
+		}
+
+		// Compare each document
+		oldDocs := make(map[string]DocumentResult)
+		for _, doc := range old.Documents {
+			oldDocs[doc.key()] = doc
+		}
+
+		for _, doc := range current.Documents {
+			oldDoc, exist := oldDocs[doc.key()]
+			if !exist {
+				continue
+			}
+
+			if len(doc.Missing) > len(oldDoc.Missing) {
+				regressions = append(regressions, Regression{
+					Mode:     current.Mode,
+					Document: doc.key(),
+					Metric:   "missing",
+					Baseline: float64(len(oldDoc.Missing)),
+					Current:  float64(len(doc.Missing)),
+					Snippets: doc.Missing,
+				})
+			}
+
+			if len(doc.Unwanted) > len(oldDoc.Unwanted) {
+				regressions = append(regressions, Regression{
+					Mode:     current.Mode,
+					Document: doc.key(),
+					Metric:   "unwanted",
+					Baseline: float64(len(oldDoc.Unwanted)),
+					Current:  float64(len(doc.Unwanted)),
+					Snippets: doc.Unwanted,
+				})
+			}
+		}
+	}
+
+	return regressions
+}
@@ -36,6 +36,7 @@ func main() {
 	opts := trafilatura.Options{
 		IncludeImages: true,
 		OriginalURL:   parsedURL,
+		ContentType:   resp.Header.Get("Content-Type"),
 	}
 
 	result, err := trafilatura.Extract(resp.Body, opts)
@@ -24,7 +24,6 @@ package trafilatura
 import (
 	"regexp"
 	"strings"
-	"unicode/utf8"
 
 	"github.com/go-shiori/dom"
 	"github.com/markusmobius/go-trafilatura/internal/etree"
@@ -32,10 +31,7 @@ import (
 	"golang.org/x/net/html"
 )
 
-var (
-	rxHtmlLang   = regexp.MustCompile(`(?i)[a-z]{2}`)
-	rxTextFilter = regexp.MustCompile(`(?i)\W*(Drucken|E-?Mail|Facebook|Flipboard|Google|Instagram|Linkedin|Mail|PDF|Pinterest|Pocket|Print|Reddit|Twitter|Whatsapp|Xing)$`)
-)
+var rxHtmlLang = regexp.MustCompile(`(?i)\b([a-z]{2,3})(?:[-_][a-z0-9]{1,8})*\b`)
 
 // checkHtmlLanguage checks HTML meta-elements for language information and
 // split the result in case there are several language.
@@ -50,8 +46,8 @@ func checkHtmlLanguage(doc *html.Node, opts Options) bool {
 
 	if htmlNode != nil && dom.HasAttribute(htmlNode, "lang") {
 		langAttr := dom.GetAttribute(htmlNode, "lang")
-		for _, lang := range rxHtmlLang.FindAllString(langAttr, -1) {
-			if lang == opts.TargetLanguage {
+		for _, match := range rxHtmlLang.FindAllStringSubmatch(langAttr, -1) {
+			if strings.EqualFold(match[1], opts.TargetLanguage) {
 				return true
 			}
 		}
@@ -64,8 +60,8 @@ func checkHtmlLanguage(doc *html.Node, opts Options) bool {
 	if len(metaNodes) > 0 {
 		for _, metaNode := range metaNodes {
 			metaContent := dom.GetAttribute(metaNode, "content")
-			for _, lang := range rxHtmlLang.FindAllString(metaContent, -1) {
-				if lang == opts.TargetLanguage {
+			for _, match := range rxHtmlLang.FindAllStringSubmatch(metaContent, -1) {
+				if strings.EqualFold(match[1], opts.TargetLanguage) {
 					return true
 				}
 			}
@@ -80,7 +76,7 @@ func checkHtmlLanguage(doc *html.Node, opts Options) bool {
 }
 
 // textFilter filters out unwanted text
-func textFilter(n *html.Node) bool {
+func textFilter(n *html.Node, opts Options) bool {
 	var testText string
 	text, tail := etree.Text(n), etree.Tail(n)
 	if text == "" && tail != "" {
@@ -93,8 +89,12 @@ func textFilter(n *html.Node) bool {
 		return true
 	}
 
+	if opts.rxBoilerplate == nil {
+		return false
+	}
+
 	for _, line := range strings.Split(testText, "\n") {
-		if rxTextFilter.MatchString(line) {
+		if opts.rxBoilerplate.MatchString(line) {
 			return true
 		}
 	}
@@ -116,7 +116,7 @@ func duplicateTest(element *html.Node, cache *lru.Cache, opts Options) bool {
 	var isDuplicate bool
 	testString := trim(etree.IterText(element, " "))
 
-	if utf8.RuneCountInString(testString) > opts.Config.MinDuplicateCheckSize {
+	if weightedLength(testString, opts.Config) > opts.Config.MinDuplicateCheckSize {
 		cacheVal, _ := cache.Get(testString)
 		if cacheVal > opts.Config.MaxDuplicateCount {
 			isDuplicate = true
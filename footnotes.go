@@ -0,0 +1,303 @@
+// This file is part of go-trafilatura, Go package for extracting readable
+// content, comments and metadata from a web page. Source available in
+// <https://github.com/markusmobius/go-trafilatura>.
+// Copyright (C) 2021 Markus Mobius
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or (at your
+// option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+// or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License
+// for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package trafilatura
+
+import (
+	nurl "net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-shiori/dom"
+	"golang.org/x/net/html"
+)
+
+// Footnote is a footnote, endnote or reference that linked from the content.
+type Footnote struct {
+	// ID is the id of the note in the page, which targeted by its markers.
+	ID string
+
+	// Label is the text of marker without brackets, e.g. "1".
+	Label string
+
+	// Marker is the text of <sup> that marks the footnote in the content, e.g. "[1]".
+	Marker string
+
+	Text string
+}
+
+var (
+	rxFootnoteLabel   = regexp.MustCompile(`^[\[(]?\s*(\d{1,4}|[a-zA-Z]|\*{1,3}|[†‡§¶#]{1,2})\s*[\])]?$`)
+	rxFootnoteID      = regexp.MustCompile(`(?i)^_?(fn|ftn|foot|endnote|note|cite[_-]?note|ref[_-]?note)`)
+	rxFootnoteList    = regexp.MustCompile(`(?i)foot|endnote|notes|reference|reflist|citation|bibliography`)
+	rxFootnoteBackref = regexp.MustCompile(`(?i)backlink|backref|reversefootnote|footnote-back`)
+)
+
+// extractFootnotes looks for footnote markers, i.e. short links in <sup> that point to
+// a note in the same page. The notes are collected then removed from the document, so
+// they are not extracted as unrelated text, while the markers are normalized into
+// <sup><a href="#id">[label]</a></sup> to keep the linkage in the content.
+func extractFootnotes(doc *html.Node) []Footnote {
+	// Map the id and anchor name of elements
+	targets := make(map[string]*html.Node)
+	for _, element := range dom.GetElementsByTagName(doc, "*") {
+		for _, attr := range []string{"id", "name"} {
+			if value := dom.GetAttribute(element, attr); value != "" {
+				if _, exist := targets[value]; !exist {
+					targets[value] = element
+				}
+			}
+		}
+	}
+
+	var footnotes []Footnote
+	var notes []*html.Node
+	noteIndexes := make(map[*html.Node]int)
+	markerIDs := make(map[string]struct{})
+
+	for _, link := range dom.GetElementsByTagName(doc, "a") {
+		// Skip the links inside notes, e.g. link back to the marker
+		if link.Parent == nil || footnoteNoteOf(link, noteIndexes) != nil {
+			continue
+		}
+
+		id, label, isMarker := footnoteMarker(link)
+		if !isMarker {
+			continue
+		}
+
+		target, exist := targets[id]
+		if !exist || containsNode(target, link) {
+			continue
+		}
+
+		note := footnoteNote(target, id)
+		if note == nil || containsNode(note, link) {
+			continue
+		}
+
+		idx, exist := noteIndexes[note]
+		if !exist {
+			idx = len(footnotes)
+			noteIndexes[note] = idx
+			notes = append(notes, note)
+			footnotes = append(footnotes, Footnote{
+				ID:     id,
+				Label:  label,
+				Marker: "[" + label + "]",
+			})
+		}
+
+		// Remember the id of marker, to remove the link back to it from the note
+		for node, depth := link, 0; node != nil && depth < 3; node, depth = node.Parent, depth+1 {
+			for _, attr := range []string{"id", "name"} {
+				if value := dom.GetAttribute(node, attr); value != "" {
+					markerIDs[value] = struct{}{}
+				}
+			}
+		}
+
+		replaceFootnoteMarker(link, footnotes[idx])
+	}
+
+	// Save the text of notes, then remove them from document
+	for i, note := range notes {
+		footnotes[i].Text = footnoteText(note, footnotes[i].ID, markerIDs)
+	}
+
+	for _, note := range notes {
+		removeFootnoteNote(note)
+	}
+
+	return footnotes
+}
+
+// footnoteMarker checks if the link is a footnote marker, and returns the id of
+// its target and its label.
+func footnoteMarker(link *html.Node) (string, string, bool) {
+	href := strings.TrimSpace(dom.GetAttribute(link, "href"))
+	if !strings.HasPrefix(href, "#") || len(href) < 2 {
+		return "", "", false
+	}
+
+	id, err := nurl.PathUnescape(href[1:])
+	if err != nil {
+		id = href[1:]
+	}
+
+	parts := rxFootnoteLabel.FindStringSubmatch(trim(dom.TextContent(link)))
+	if parts == nil {
+		return "", "", false
+	}
+
+	// Marker is usually put in <sup>, or at least has a footnote id or role
+	isMarker := dom.GetAttribute(link, "role") == "doc-noteref" ||
+		dom.GetAttribute(link, "rel") == "footnote" ||
+		dom.QuerySelector(link, "sup") != nil ||
+		rxFootnoteID.MatchString(id)
+
+	for parent, depth := link.Parent, 0; !isMarker && parent != nil && depth < 2; parent, depth = parent.Parent, depth+1 {
+		isMarker = dom.TagName(parent) == "sup"
+	}
+
+	return id, parts[1], isMarker
+}
+
+// footnoteNote returns the element that contains the note for the target of marker.
+// To make sure the marker doesn't point to unrelated section (e.g. comments), the note
+// must be a list item, paragraph or small element, and either its id looks like a note
+// or it's put within list of footnotes or references.
+func footnoteNote(target *html.Node, id string) *html.Node {
+	// Inline anchor is usually put at the start of the note
+	note := target
+	for note != nil {
+		switch dom.TagName(note) {
+		case "a", "span", "sup", "sub", "b", "strong", "i", "em", "small", "cite":
+			note = note.Parent
+			continue
+		}
+		break
+	}
+
+	switch dom.TagName(note) {
+	case "", "html", "body", "main", "article", "header", "h1", "h2", "h3", "h4", "h5", "h6":
+		return nil
+	case "li", "p":
+	default:
+		if utf8.RuneCountInString(trim(dom.TextContent(note))) > 500 ||
+			dom.QuerySelector(note, "h1, h2, h3, h4, h5, h6, article, section") != nil {
+			return nil
+		}
+	}
+
+	if rxFootnoteID.MatchString(id) || inFootnoteList(note) {
+		return note
+	}
+
+	return nil
+}
+
+// inFootnoteList checks if the note or its close ancestors is marked as note,
+// or list of footnotes or references.
+func inFootnoteList(note *html.Node) bool {
+	for node, depth := note, 0; node != nil && depth < 4; node, depth = node.Parent, depth+1 {
+		switch dom.TagName(node) {
+		case "html", "body", "main", "article":
+			return false
+		}
+
+		switch dom.GetAttribute(node, "role") {
+		case "doc-footnote", "doc-endnote", "doc-endnotes", "doc-bibliography":
+			return true
+		}
+
+		if rxFootnoteList.MatchString(dom.ClassName(node) + " " + dom.ID(node)) {
+			return true
+		}
+	}
+	return false
+}
+
+// footnoteNoteOf returns the note that contains the element.
+func footnoteNoteOf(element *html.Node, noteIndexes map[*html.Node]int) *html.Node {
+	for parent := element.Parent; parent != nil; parent = parent.Parent {
+		if _, isNote := noteIndexes[parent]; isNote {
+			return parent
+		}
+	}
+	return nil
+}
+
+// containsNode checks if node is the element itself or one of its descendants.
+func containsNode(element, node *html.Node) bool {
+	for ; node != nil; node = node.Parent {
+		if node == element {
+			return true
+		}
+	}
+	return false
+}
+
+// replaceFootnoteMarker replaces the marker, along with <sup> that wraps it, with a
+// normalized marker for the footnote.
+func replaceFootnoteMarker(link *html.Node, footnote Footnote) {
+	marker := link
+	if parent := link.Parent; dom.TagName(parent) == "sup" && len(dom.Children(parent)) == 1 &&
+		trim(dom.TextContent(parent)) == trim(dom.TextContent(link)) {
+		marker = parent
+	}
+
+	newLink := dom.CreateElement("a")
+	dom.SetAttribute(newLink, "href", "#"+footnote.ID)
+	dom.AppendChild(newLink, dom.CreateTextNode(footnote.Marker))
+
+	newMarker := dom.CreateElement("sup")
+	dom.AppendChild(newMarker, newLink)
+
+	if marker.Parent != nil {
+		marker.Parent.InsertBefore(newMarker, marker)
+		marker.Parent.RemoveChild(marker)
+	}
+}
+
+// footnoteText returns the text of note, without the anchor and links back to the markers.
+func footnoteText(note *html.Node, id string, markerIDs map[string]struct{}) string {
+	clone := dom.Clone(note, true)
+
+	var toRemove []*html.Node
+	for _, element := range dom.GetElementsByTagName(clone, "*") {
+		attrID := dom.GetAttribute(element, "id")
+		attrName := dom.GetAttribute(element, "name")
+		href := dom.GetAttribute(element, "href")
+		className := dom.GetAttribute(element, "class")
+
+		_, isBackLink := markerIDs[strings.TrimPrefix(href, "#")]
+		switch {
+		case strIn(dom.TagName(element), "script", "style", "noscript", "template"),
+			attrID == id && dom.TagName(element) == "a",
+			attrName == id,
+			strings.HasPrefix(href, "#") && isBackLink,
+			dom.GetAttribute(element, "role") == "doc-backlink",
+			rxFootnoteBackref.MatchString(className):
+			toRemove = append(toRemove, element)
+		}
+	}
+	dom.RemoveNodes(toRemove, nil)
+
+	return strings.TrimSpace(RenderText(clone, TextOptions{}))
+}
+
+// removeFootnoteNote removes the note from document, along with its parents
+// that become empty, e.g. the list of references.
+func removeFootnoteNote(note *html.Node) {
+	for note.Parent != nil {
+		parent := note.Parent
+		parent.RemoveChild(note)
+
+		switch dom.TagName(parent) {
+		case "html", "body", "main", "article":
+			return
+		}
+
+		if trim(dom.TextContent(parent)) != "" {
+			return
+		}
+		note = parent
+	}
+}
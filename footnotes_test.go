@@ -0,0 +1,105 @@
+// This file is part of go-trafilatura, Go package for extracting readable
+// content, comments and metadata from a web page. Source available in
+// <https://github.com/markusmobius/go-trafilatura>.
+// Copyright (C) 2021 Markus Mobius
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or (at your
+// option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+// or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License
+// for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package trafilatura
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-shiori/dom"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Footnotes(t *testing.T) {
+	// Wikipedia style, with a note that referenced twice
+	doc := docFromStr(`<html><body><div id="content">
+		<p>First claim.<sup id="cite_ref-a_1-0" class="reference"><a href="#cite_note-a-1">[1]</a></sup>
+		Second claim.<sup id="cite_ref-2" class="reference"><a href="#cite_note-2">[2]</a></sup>
+		Again.<sup id="cite_ref-a_1-1" class="reference"><a href="#cite_note-a-1">[1]</a></sup></p>
+		<h2>References</h2>
+		<div class="reflist"><ol class="references">
+			<li id="cite_note-a-1"><span class="mw-cite-backlink">^ <a href="#cite_ref-a_1-0">a</a> <a href="#cite_ref-a_1-1">b</a></span>
+				<span class="reference-text"><style>.citation{}</style>Doe, J. (2020). A book.</span></li>
+			<li id="cite_note-2"><span class="mw-cite-backlink"><a href="#cite_ref-2">^</a></span>
+				<span class="reference-text">Roe, R. (2021). A paper.</span></li>
+		</ol></div>
+		<p><a href="#section-2">2</a> <a href="#top">Top</a></p>
+		</div></body></html>`)
+
+	footnotes := extractFootnotes(doc)
+	assert.Equal(t, []Footnote{
+		{ID: "cite_note-a-1", Label: "1", Marker: "[1]", Text: "Doe, J. (2020). A book."},
+		{ID: "cite_note-2", Label: "2", Marker: "[2]", Text: "Roe, R. (2021). A paper."},
+	}, footnotes)
+
+	// Markers are normalized and the notes are removed
+	assert.Len(t, dom.QuerySelectorAll(doc, `sup > a[href="#cite_note-a-1"]`), 2)
+	assert.Nil(t, dom.QuerySelector(doc, ".reflist"))
+	assert.NotNil(t, dom.QuerySelector(doc, `a[href="#section-2"]`))
+
+	// Markdown renderer style, where the marker is not wrapped in brackets
+	doc = docFromStr(`<html><body><article>
+		<p>Text<sup id="fnref:1"><a href="#fn:1" class="footnote-ref" role="doc-noteref">1</a></sup>.</p>
+		<div class="footnotes" role="doc-endnotes"><ol>
+			<li id="fn:1"><p>The note. <a href="#fnref:1" class="reversefootnote" role="doc-backlink">&#8617;</a></p></li>
+		</ol></div></article></body></html>`)
+
+	footnotes = extractFootnotes(doc)
+	assert.Equal(t, []Footnote{{ID: "fn:1", Label: "1", Marker: "[1]", Text: "The note."}}, footnotes)
+	assert.Equal(t, "[1]", dom.TextContent(dom.QuerySelector(doc, "sup")))
+
+	// Word style, where the note is marked by named anchor
+	doc = docFromStr(`<html><body>
+		<p>Word text.<a href="#_ftn1" name="_ftnref1"><sup>[1]</sup></a></p>
+		<div id="ftn1"><p><a href="#_ftnref1" name="_ftn1">[1]</a> The Word note.</p></div>
+		</body></html>`)
+
+	footnotes = extractFootnotes(doc)
+	assert.Equal(t, []Footnote{{ID: "_ftn1", Label: "1", Marker: "[1]", Text: "The Word note."}}, footnotes)
+	assert.Nil(t, dom.QuerySelector(doc, "#ftn1"))
+
+	// Short links in <sup> that don't point to a note are not footnote markers
+	doc = docFromStr(`<html><body><article>
+		<p>Read the discussion.<sup><a href="#comments">3</a></sup>
+		See the item.<sup><a href="#item">2</a></sup>
+		Old note.<a href="#preface">1</a></p>
+		<ul class="menu"><li id="item">Menu item</li></ul>
+		<section id="comments"><h2>Comments</h2><p>Nice article!</p></section>
+		<p id="preface">Unrelated preface.</p>
+		</article></body></html>`)
+
+	assert.Empty(t, extractFootnotes(doc))
+	assert.NotNil(t, dom.QuerySelector(doc, "#comments"))
+	assert.NotNil(t, dom.QuerySelector(doc, "#item"))
+	assert.NotNil(t, dom.QuerySelector(doc, "#preface"))
+	assert.Len(t, dom.QuerySelectorAll(doc, `a[href="#comments"]`), 1)
+
+	// Extracted content keeps the markers, while the notes are only in footnotes
+	paragraph := strings.Repeat("Lorem ipsum dolor sit amet, consectetur adipiscing elit. ", 5)
+	rawHTML := `<html><body><article><p>` + paragraph + `Claim.<sup><a href="#fn-1">1</a></sup></p>
+		<p>` + paragraph + `</p><ol class="footnotes"><li id="fn-1">Source of the claim.</li></ol>
+		</article></body></html>`
+
+	result, err := Extract(strings.NewReader(rawHTML), Options{NoFallback: true})
+	assert.Nil(t, err)
+	assert.Len(t, result.Footnotes, 1)
+	assert.Equal(t, "Source of the claim.", result.Footnotes[0].Text)
+	assert.Contains(t, result.ContentText, "Claim. [1]")
+	assert.NotContains(t, result.ContentText, "Source of the claim.")
+}
@@ -0,0 +1,123 @@
+// This file is part of go-trafilatura, Go package for extracting readable
+// content, comments and metadata from a web page. Source available in
+// <https://github.com/markusmobius/go-trafilatura>.
+// Copyright (C) 2021 Markus Mobius
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or (at your
+// option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+// or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License
+// for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program. If not, see <https://www.gnu.org/licenses/>.
+
+//go:build go1.18
+// +build go1.18
+
+package trafilatura
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-shiori/dom"
+	"golang.org/x/net/html"
+)
+
+// Run the fuzz targets using `go test -run none -fuzz <target>`, e.g. `-fuzz FuzzExtract`.
+
+// maxFuzzSeedSize is the max size of test file that used as seed. Large seeds make each
+// execution slow and stall the input minimization, so they are skipped.
+const maxFuzzSeedSize = 64 * 1024
+
+func FuzzExtract(f *testing.F) {
+	for _, seed := range fuzzSeeds(f) {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// Limit errors are expected, only panic that fails the target
+		_, err := Extract(bytes.NewReader(data), Options{
+			MaxInputBytes: 1024 * 1024,
+			MaxNodes:      20000,
+			MaxDepth:      200,
+			MaxDuration:   5 * time.Second,
+		})
+
+		var panicErr *PanicError
+		if errors.As(err, &panicErr) {
+			t.Fatalf("%v\n%s", panicErr, panicErr.Stack)
+		}
+	})
+}
+
+func FuzzMetadata(f *testing.F) {
+	for _, seed := range fuzzSeeds(f) {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		doc, err := html.Parse(bytes.NewReader(data))
+		if err != nil {
+			return
+		}
+
+		extractMetadata(doc, Options{})
+	})
+}
+
+func FuzzJsonLd(f *testing.F) {
+	// Use the JSON+LD scripts in test files as seeds
+	for _, seed := range fuzzSeeds(f) {
+		doc, err := html.Parse(bytes.NewReader(seed))
+		if err != nil {
+			continue
+		}
+
+		for _, script := range dom.QuerySelectorAll(doc, `script[type="application/ld+json"]`) {
+			f.Add(dom.TextContent(script))
+		}
+	}
+
+	f.Add(`{"@type":"NewsArticle","author":[{"@type":"Person","name":"A"}],"headline":"T"}`)
+	f.Add(`{"@graph":[{"@type":"WebPage","publisher":{"name":"P"}},null,[1,"x"]]}`)
+
+	f.Fuzz(func(t *testing.T, jsonLd string) {
+		// Make sure the JSON+LD stays inside the script element
+		jsonLd = strings.ReplaceAll(jsonLd, "</", "<\\/")
+		rawHTML := `<html><head><script type="application/ld+json">` + jsonLd + `</script></head></html>`
+		extractJsonLd(docFromStr(rawHTML), Metadata{})
+	})
+}
+
+// fuzzSeeds returns content of HTML files in test-files, used as seed corpus.
+func fuzzSeeds(f *testing.F) [][]byte {
+	var seeds [][]byte
+	for _, dir := range []string{"simple", "mock"} {
+		paths, err := filepath.Glob(filepath.Join("test-files", dir, "*.html"))
+		if err != nil {
+			f.Fatal(err)
+		}
+
+		for _, path := range paths {
+			content, err := ioutil.ReadFile(path)
+			if err != nil {
+				f.Fatal(err)
+			}
+			if len(content) <= maxFuzzSeedSize {
+				seeds = append(seeds, content)
+			}
+		}
+	}
+	return seeds
+}
@@ -0,0 +1,191 @@
+// This file is part of go-trafilatura, Go package for extracting readable
+// content, comments and metadata from a web page. Source available in
+// <https://github.com/markusmobius/go-trafilatura>.
+// Copyright (C) 2021 Markus Mobius
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or (at your
+// option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+// or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License
+// for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package trafilatura
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	nurl "net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/markusmobius/go-trafilatura/internal/etree"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// updateGolden is used to refresh the golden files, e.g. after the extraction result
+// is intentionally changed. Run it with `go test -run Test_Golden -update`.
+var updateGolden = flag.Bool("update", false, "update golden files in testdata/golden")
+
+const goldenDir = "testdata/golden"
+
+// Test_Golden extracts every page in mock directory and compares the full result
+// (metadata, text and HTML of content and comments) with the golden files.
+func Test_Golden(t *testing.T) {
+	// Find the original URL for each mock file
+	mockURLs := make(map[string]string)
+	for _, mockFiles := range []map[string]string{rwMockFiles, metadataMockFiles} {
+		for url, name := range mockFiles {
+			mockURLs[name] = url
+		}
+	}
+
+	mockDir := filepath.Join("test-files", "mock")
+	fileInfos, err := ioutil.ReadDir(mockDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if *updateGolden {
+		if err := os.MkdirAll(goldenDir, os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, info := range fileInfos {
+		name := info.Name()
+		if info.IsDir() || !strings.HasSuffix(name, ".html") {
+			continue
+		}
+
+		t.Run(name, func(t *testing.T) {
+			actual, err := extractGolden(filepath.Join(mockDir, name), mockURLs[name])
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			goldenPath := filepath.Join(goldenDir, name+".golden")
+			if *updateGolden {
+				if err := ioutil.WriteFile(goldenPath, []byte(actual), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			expected, err := ioutil.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatalf("%v (run `go test -run Test_Golden -update` to create it)", err)
+			}
+
+			if diff := goldenDiff(string(expected), actual); diff != "" {
+				t.Errorf("result differs from %s:\n%s", goldenPath, diff)
+			}
+		})
+	}
+}
+
+// extractGolden extracts the mock file and renders the result as plain text that
+// is easy to read and compare line by line.
+func extractGolden(path string, url string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	opts := Options{}
+	if url != "" {
+		opts.OriginalURL, _ = nurl.ParseRequestURI(url)
+	}
+
+	result, err := Extract(f, opts)
+	if err != nil {
+		// Failed extraction is a valid result as well
+		return fmt.Sprintf("error: %v\n", err), nil
+	}
+
+	var date string
+	if !result.Metadata.Date.IsZero() {
+		date = result.Metadata.Date.Format("2006-01-02")
+	}
+
+	sb := new(strings.Builder)
+	fmt.Fprintf(sb, "url: %s\n", result.Metadata.URL)
+	fmt.Fprintf(sb, "hostname: %s\n", result.Metadata.Hostname)
+	fmt.Fprintf(sb, "title: %s\n", result.Metadata.Title)
+	fmt.Fprintf(sb, "author: %s\n", result.Metadata.Author)
+	fmt.Fprintf(sb, "description: %s\n", result.Metadata.Description)
+	fmt.Fprintf(sb, "sitename: %s\n", result.Metadata.Sitename)
+	fmt.Fprintf(sb, "date: %s\n", date)
+	fmt.Fprintf(sb, "categories: %s\n", strings.Join(result.Metadata.Categories, "; "))
+	fmt.Fprintf(sb, "tags: %s\n", strings.Join(result.Metadata.Tags, "; "))
+	fmt.Fprintf(sb, "license: %s\n", result.Metadata.License)
+	fmt.Fprintf(sb, "encoding: %s\n", result.Encoding)
+
+	writeSection := func(title string, content string) {
+		content = strings.TrimSpace(content)
+		fmt.Fprintf(sb, "\n===== %s =====\n", title)
+		if content != "" {
+			sb.WriteString(content + "\n")
+		}
+	}
+
+	writeSection("content text", goldenWrap(result.ContentText, 100))
+	writeSection("comments text", goldenWrap(result.CommentsText, 100))
+	if result.ContentNode != nil {
+		writeSection("content html", etree.ToString(result.ContentNode, true))
+	}
+	if result.CommentsNode != nil {
+		writeSection("comments html", etree.ToString(result.CommentsNode, true))
+	}
+
+	return sb.String(), nil
+}
+
+// goldenWrap wraps the long lines in text, so a small change in extracted text
+// only shows up as a small diff.
+func goldenWrap(text string, width int) string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		var current string
+		for _, word := range strings.Fields(line) {
+			if current != "" && len(current)+len(word)+1 > width {
+				lines = append(lines, current)
+				current = ""
+			}
+
+			if current != "" {
+				current += " "
+			}
+			current += word
+		}
+		lines = append(lines, current)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// goldenDiff returns unified diff between the expected and actual result,
+// or empty string if both are the same.
+func goldenDiff(expected, actual string) string {
+	if expected == actual {
+		return ""
+	}
+
+	diff, _ := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(expected),
+		B:        difflib.SplitLines(actual),
+		FromFile: "expected",
+		ToFile:   "actual",
+		Context:  2,
+	})
+	return diff
+}
@@ -24,7 +24,6 @@ package trafilatura
 import (
 	"regexp"
 	"strings"
-	"unicode/utf8"
 
 	"github.com/go-shiori/dom"
 	"github.com/markusmobius/go-trafilatura/internal/etree"
@@ -33,25 +32,12 @@ import (
 	"golang.org/x/net/html"
 )
 
-var rxWords = regexp.MustCompile(`\w`)
+var rxWords = regexp.MustCompile(`[\p{L}\p{N}]`)
 
 // docCleaning cleans the document by discarding unwanted elements
 func docCleaning(doc *html.Node, excludeTables, includeImages bool) {
 	// Determine cleaning strategy
-	cleaningList := duplicateMap(tagsToClean)
-	strippingList := duplicateMap(tagsToStrip)
-
-	if excludeTables {
-		cleaningList["table"] = struct{}{}
-	}
-
-	if includeImages {
-		// Many websites have <img> inside <figure> or <picture> or <source> tag
-		delete(cleaningList, "figure")
-		delete(cleaningList, "picture")
-		delete(cleaningList, "source")
-		delete(strippingList, "img")
-	}
+	cleaningList, strippingList := cleaningStrategy(excludeTables, includeImages)
 
 	// Remove nodes in cleaning list including its children
 	for tagName := range cleaningList {
@@ -68,6 +54,27 @@ func docCleaning(doc *html.Node, excludeTables, includeImages bool) {
 	pruneHTML(doc)
 }
 
+// cleaningStrategy returns the tags that removed along with their children
+// and the tags that stripped while keeping their children by docCleaning.
+func cleaningStrategy(excludeTables, includeImages bool) (cleaningList, strippingList map[string]struct{}) {
+	cleaningList = duplicateMap(tagsToClean)
+	strippingList = duplicateMap(tagsToStrip)
+
+	if excludeTables {
+		cleaningList["table"] = struct{}{}
+	}
+
+	if includeImages {
+		// Many websites have <img> inside <figure> or <picture> or <source> tag
+		delete(cleaningList, "figure")
+		delete(cleaningList, "picture")
+		delete(cleaningList, "source")
+		delete(strippingList, "img")
+	}
+
+	return cleaningList, strippingList
+}
+
 // removeHtmlCommentNode removes all `html.CommentNode` in document.
 func removeHtmlCommentNode(doc *html.Node) {
 	// Find all comment nodes
@@ -100,8 +107,10 @@ func pruneHTML(doc *html.Node) {
 			continue
 		}
 
+		// Inside code, the text after empty element is part of the code (e.g. empty
+		// <span> from syntax highlighter), so it must be kept
 		if len(dom.ChildNodes(subElement)) == 0 {
-			etree.Remove(subElement)
+			etree.Remove(subElement, insideCode(subElement))
 		}
 	}
 }
@@ -173,7 +182,7 @@ func handleTextNode(node *html.Node, cache *lru.Cache, fixComments bool, opts Op
 	etree.SetTail(node, tail)
 
 	if rxWords.MatchString(text) {
-		if textFilter(node) {
+		if textFilter(node, opts) {
 			return nil
 		}
 
@@ -189,7 +198,7 @@ func handleTextNode(node *html.Node, cache *lru.Cache, fixComments bool, opts Op
 
 // linkDensityTest check whether sections will be removed because it's rich in
 // links (probably boilerplate)
-func linkDensityTest(element *html.Node) ([]*html.Node, bool) {
+func linkDensityTest(element *html.Node, opts Options) ([]*html.Node, bool) {
 	// Fetch links in node
 	links := dom.GetElementsByTagName(element, "a")
 	if len(links) == 0 {
@@ -211,10 +220,10 @@ func linkDensityTest(element *html.Node) ([]*html.Node, bool) {
 
 	// Check if text of this node is within limit
 	text := trim(dom.TextContent(element))
-	textLength := utf8.RuneCountInString(text)
+	textLength := weightedLength(text, opts.Config)
 	if textLength < limitLength {
 		// Collect link info
-		linkLength, nShortLinks, nonEmptyLinks := collectLinkInfo(links)
+		linkLength, nShortLinks, nonEmptyLinks := collectLinkInfo(links, opts)
 		nNonEmptyLinks := len(nonEmptyLinks)
 		if nNonEmptyLinks == 0 {
 			return nonEmptyLinks, true
@@ -232,7 +241,7 @@ func linkDensityTest(element *html.Node) ([]*html.Node, bool) {
 
 // linkDensityTestTables check whether a table will be removed because
 // it's rich in links (probably boilerplate)
-func linkDensityTestTables(table *html.Node) bool {
+func linkDensityTestTables(table *html.Node, opts Options) bool {
 	// Fetch links in table
 	links := dom.GetElementsByTagName(table, "a")
 	if len(links) == 0 {
@@ -241,10 +250,10 @@ func linkDensityTestTables(table *html.Node) bool {
 
 	// Check text length
 	text := trim(dom.TextContent(table))
-	textLength := utf8.RuneCountInString(text)
+	textLength := weightedLength(text, opts.Config)
 	if textLength > 250 {
 		// Collect link info
-		linkLength, nShortLinks, nonEmptyLinks := collectLinkInfo(links)
+		linkLength, nShortLinks, nonEmptyLinks := collectLinkInfo(links, opts)
 		nNonEmptyLinks := len(nonEmptyLinks)
 		if nNonEmptyLinks == 0 {
 			return true
@@ -264,10 +273,10 @@ func linkDensityTestTables(table *html.Node) bool {
 }
 
 // collectLinkInfo collects heuristics on link text.
-func collectLinkInfo(links []*html.Node) (linkLength, nShortLinks int, nonEmptyLinks []*html.Node) {
+func collectLinkInfo(links []*html.Node, opts Options) (linkLength, nShortLinks int, nonEmptyLinks []*html.Node) {
 	for _, link := range links {
 		text := trim(dom.TextContent(link))
-		textLength := utf8.RuneCountInString(text)
+		textLength := weightedLength(text, opts.Config)
 		if textLength == 0 {
 			continue
 		}
@@ -308,7 +317,7 @@ func processNode(element *html.Node, cache *lru.Cache, opts Options) *html.Node
 
 	// Content checks
 	if text != "" || tail != "" {
-		if textFilter(element) {
+		if textFilter(element, opts) {
 			return nil
 		}
 
@@ -347,6 +356,13 @@ func postCleaning(doc *html.Node) {
 				continue
 			}
 
+			// Keep the language hint of code block
+			if attr.Key == "class" && dom.TagName(element) == "code" &&
+				strings.HasPrefix(attr.Val, "language-") {
+				newAttr = append(newAttr, attr)
+				continue
+			}
+
 			// Remove id, class, data and event attributes
 			switch {
 			case attr.Key == "id",
@@ -154,6 +154,10 @@ func SetTail(element *html.Node, tail string) {
 
 // TailNodes returns the list of tail nodes for the element.
 func TailNodes(element *html.Node) []*html.Node {
+	if element == nil {
+		return nil
+	}
+
 	var nodes []*html.Node
 	for next := element.NextSibling; next != nil; next = next.NextSibling {
 		if next.Type == html.ElementNode {
@@ -168,6 +172,10 @@ func TailNodes(element *html.Node) []*html.Node {
 
 // Append appends single subelement into the node.
 func Append(node, subelement *html.Node) {
+	if node == nil || subelement == nil {
+		return
+	}
+
 	// Fetch the tail nodes of the subelement
 	tailNodes := TailNodes(subelement)
 
@@ -0,0 +1,225 @@
+// This file is part of go-trafilatura, Go package for extracting readable
+// content, comments and metadata from a web page. Source available in
+// <https://github.com/markusmobius/go-trafilatura>.
+// Copyright (C) 2021 Markus Mobius
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or (at your
+// option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+// or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License
+// for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package trafilatura
+
+import (
+	"io"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// limitedReader is like io.LimitedReader, except it returns LimitError
+// instead of EOF once the limit is exceeded.
+type limitedReader struct {
+	Reader    io.Reader
+	Limit     int64
+	remaining int64
+}
+
+func newLimitedReader(r io.Reader, limit int64) *limitedReader {
+	return &limitedReader{Reader: r, Limit: limit, remaining: limit}
+}
+
+func (lr *limitedReader) Read(p []byte) (int, error) {
+	// Allow reading one more byte, to see if the input is larger than limit
+	if lr.remaining < 0 {
+		return 0, &LimitError{Kind: LimitInputBytes, Limit: lr.Limit}
+	}
+
+	if int64(len(p)) > lr.remaining+1 {
+		p = p[:lr.remaining+1]
+	}
+
+	n, err := lr.Reader.Read(p)
+	lr.remaining -= int64(n)
+	if lr.remaining < 0 {
+		return 0, &LimitError{Kind: LimitInputBytes, Limit: lr.Limit}
+	}
+
+	return n, err
+}
+
+// checkTokenLimits estimates the number of nodes and the nesting depth of the document
+// by tokenizing it, so a huge or deeply nested document can be rejected before its tree
+// is built. The tags that closed implicitly by the parser are closed here as well, and
+// the whitespaces are not counted, so the estimate doesn't exceed the actual tree which
+// is checked again by checkTreeLimits after parsing.
+func checkTokenLimits(r io.Reader, opts Options) error {
+	if opts.MaxNodes <= 0 && opts.MaxDepth <= 0 {
+		return nil
+	}
+
+	var nNodes int
+	var stack []string
+	tokenizer := html.NewTokenizer(r)
+
+	for {
+		tokenType := tokenizer.Next()
+		switch tokenType {
+		case html.ErrorToken:
+			if err := tokenizer.Err(); err != io.EOF {
+				return err
+			}
+			return nil
+
+		case html.TextToken:
+			if strings.TrimSpace(string(tokenizer.Raw())) == "" {
+				continue
+			}
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, _ := tokenizer.TagName()
+			tagName := string(name)
+			stack = closeImplicitTags(stack, tagName)
+
+			_, isVoid := voidElements[tagName]
+			if tokenType == html.StartTagToken && !isVoid {
+				stack = append(stack, tagName)
+				if opts.MaxDepth > 0 && len(stack) > opts.MaxDepth {
+					return &LimitError{Kind: LimitDepth, Limit: int64(opts.MaxDepth)}
+				}
+			}
+
+		case html.EndTagToken:
+			// Close the tag along with its unclosed children, if it's open
+			name, _ := tokenizer.TagName()
+			for i := len(stack) - 1; i >= 0; i-- {
+				if stack[i] == string(name) {
+					stack = stack[:i]
+					break
+				}
+			}
+			continue
+
+		case html.DoctypeToken:
+			continue
+		}
+
+		nNodes++
+		if opts.MaxNodes > 0 && nNodes > opts.MaxNodes {
+			return &LimitError{Kind: LimitNodes, Limit: int64(opts.MaxNodes)}
+		}
+	}
+}
+
+// closeImplicitTags closes the open tags that can't contain the new tag, e.g. <p> is
+// closed by a block element, and <li> is closed by the next <li> in the same list.
+func closeImplicitTags(stack []string, tagName string) []string {
+	if len(stack) == 0 {
+		return stack
+	}
+
+	var closedTags []string
+	var scopeTags []string
+	switch tagName {
+	case "li":
+		closedTags, scopeTags = []string{"li"}, []string{"ul", "ol", "menu"}
+	case "dt", "dd":
+		closedTags, scopeTags = []string{"dt", "dd"}, []string{"dl"}
+	case "tr":
+		closedTags, scopeTags = []string{"tr", "td", "th"}, []string{"table", "tbody", "thead", "tfoot"}
+	case "td", "th":
+		closedTags, scopeTags = []string{"td", "th"}, []string{"tr", "table"}
+	case "option", "optgroup":
+		closedTags, scopeTags = []string{"option"}, []string{"select", "datalist"}
+	case "a", "button", "form", "nobr":
+		closedTags = []string{tagName}
+	}
+
+	if _, closesP := paragraphClosers[tagName]; closesP {
+		closedTags = append(closedTags, "p")
+	}
+
+	// Find the outermost tag that closed, without leaving the current scope
+	closeIdx := len(stack)
+	for i := len(stack) - 1; i >= 0 && len(closedTags) > 0; i-- {
+		if strIn(stack[i], closedTags...) {
+			closeIdx = i
+		} else if strIn(stack[i], scopeTags...) ||
+			strIn(stack[i], "table", "td", "th", "html", "body", "template") {
+			break
+		}
+	}
+
+	return stack[:closeIdx]
+}
+
+var voidElements = sliceToMap(
+	"area", "base", "br", "col", "embed", "hr", "img", "input", "keygen",
+	"link", "meta", "param", "source", "track", "wbr")
+
+var paragraphClosers = sliceToMap(
+	"address", "article", "aside", "blockquote", "details", "dialog", "dir", "div",
+	"dl", "fieldset", "figcaption", "figure", "footer", "form", "h1", "h2", "h3",
+	"h4", "h5", "h6", "header", "hgroup", "hr", "li", "dd", "dt", "main", "menu",
+	"nav", "ol", "p", "pre", "section", "summary", "table", "ul")
+
+// checkTreeLimits checks the number of nodes and the nesting depth of the document.
+// The tree is walked without recursion, so deeply nested document is safe to check.
+func checkTreeLimits(doc *html.Node, opts Options) error {
+	if opts.MaxNodes <= 0 && opts.MaxDepth <= 0 {
+		return nil
+	}
+
+	var nNodes, depth int
+	for node := doc; node != nil; {
+		nNodes++
+		if opts.MaxNodes > 0 && nNodes > opts.MaxNodes {
+			return &LimitError{Kind: LimitNodes, Limit: int64(opts.MaxNodes)}
+		}
+
+		// Go down to the first child
+		if node.FirstChild != nil {
+			node = node.FirstChild
+			depth++
+			if opts.MaxDepth > 0 && depth > opts.MaxDepth {
+				return &LimitError{Kind: LimitDepth, Limit: int64(opts.MaxDepth)}
+			}
+			continue
+		}
+
+		// Go up until we find the next sibling
+		for node != doc && node.NextSibling == nil {
+			node = node.Parent
+			depth--
+		}
+
+		if node == doc {
+			break
+		}
+		node = node.NextSibling
+	}
+
+	return nil
+}
+
+// deadlineExceeded checks if the time budget for the extraction has run out.
+func deadlineExceeded(opts Options) bool {
+	return !opts.deadline.IsZero() && time.Now().After(opts.deadline)
+}
+
+// checkDeadline returns LimitError if the time budget for the extraction has run out.
+func checkDeadline(opts Options) error {
+	if deadlineExceeded(opts) {
+		return &LimitError{Kind: LimitDuration, Limit: int64(opts.MaxDuration)}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+// This file is part of go-trafilatura, Go package for extracting readable
+// content, comments and metadata from a web page. Source available in
+// <https://github.com/markusmobius/go-trafilatura>.
+// Copyright (C) 2021 Markus Mobius
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or (at your
+// option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+// or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License
+// for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package trafilatura
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Limits(t *testing.T) {
+	// Helper function
+	limitKind := func(err error) LimitKind {
+		var limitErr *LimitError
+		if errors.As(err, &limitErr) {
+			return limitErr.Kind
+		}
+		return ""
+	}
+
+	paragraph := "<p>" + strings.Repeat("Lorem ipsum dolor sit amet, consectetur adipiscing elit. ", 10) + "</p>"
+	rawHTML := "<html><body><article>" + strings.Repeat(paragraph, 3) + "</article></body></html>"
+
+	// Input size
+	opts := Options{NoFallback: true, MaxInputBytes: int64(len(rawHTML))}
+	_, err := Extract(strings.NewReader(rawHTML), opts)
+	assert.Nil(t, err)
+
+	opts.MaxInputBytes--
+	_, err = Extract(strings.NewReader(rawHTML), opts)
+	assert.Equal(t, LimitInputBytes, limitKind(err))
+
+	// Number of nodes, i.e. html, head, body, article and 3 paragraphs with their text
+	opts = Options{NoFallback: true, MaxNodes: 11}
+	_, err = Extract(strings.NewReader(rawHTML), opts)
+	assert.Nil(t, err)
+
+	opts.MaxNodes--
+	_, err = Extract(strings.NewReader(rawHTML), opts)
+	assert.Equal(t, LimitNodes, limitKind(err))
+
+	// Nesting depth, checked on deeply nested document
+	opts = Options{NoFallback: true, MaxDepth: 100}
+	_, err = Extract(strings.NewReader(rawHTML), opts)
+	assert.Nil(t, err)
+
+	deepHTML := strings.Repeat("<div>", 10000) + paragraph + strings.Repeat("</div>", 10000)
+	_, err = Extract(strings.NewReader(deepHTML), opts)
+	assert.Equal(t, LimitDepth, limitKind(err))
+
+	// Huge and deep documents are rejected by tokenizer, before their tree is built
+	hugeHTML := "<html><body>" + strings.Repeat("<p>a</p>", 100) + "</body></html>"
+	assert.Equal(t, LimitNodes, limitKind(checkTokenLimits(strings.NewReader(hugeHTML), Options{MaxNodes: 150})))
+	assert.Equal(t, LimitDepth, limitKind(checkTokenLimits(strings.NewReader(deepHTML), Options{MaxDepth: 100})))
+
+	// Tags that closed implicitly and whitespaces don't inflate the estimate
+	implicitHTML := "<html><body><table>" + strings.Repeat("<tr><td>a <td><a>b<a>c</a>\n", 200) +
+		"</table><ul>" + strings.Repeat("<li><p>item\n ", 200) + "</ul>" +
+		strings.Repeat("<p>text<br><div>block</div>\n", 200) + "</body></html>"
+
+	treeOpts := Options{MaxDepth: 8, MaxNodes: 4000}
+	assert.Nil(t, checkTokenLimits(strings.NewReader(implicitHTML), treeOpts))
+	assert.Nil(t, checkTreeLimits(docFromStr(implicitHTML), treeOpts))
+
+	// Time budget
+	opts = Options{MaxDuration: time.Minute}
+	_, err = Extract(strings.NewReader(rawHTML), opts)
+	assert.Nil(t, err)
+
+	opts.MaxDuration = time.Nanosecond
+	_, err = Extract(strings.NewReader(rawHTML), opts)
+	assert.Equal(t, LimitDuration, limitKind(err))
+
+	_, err = ExtractDocument(docFromStr(rawHTML), opts)
+	assert.Equal(t, LimitDuration, limitKind(err))
+	assert.Equal(t, "extraction exceeded time budget of 1ns", err.Error())
+}
@@ -0,0 +1,465 @@
+// This file is part of go-trafilatura, Go package for extracting readable
+// content, comments and metadata from a web page. Source available in
+// <https://github.com/markusmobius/go-trafilatura>.
+// Copyright (C) 2021 Markus Mobius
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or (at your
+// option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+// or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License
+// for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package trafilatura
+
+import (
+	nurl "net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-shiori/dom"
+	"golang.org/x/net/html"
+)
+
+// MediaKind is the kind of media that embedded in the page.
+type MediaKind string
+
+const (
+	MediaVideo   MediaKind = "video"
+	MediaAudio   MediaKind = "audio"
+	MediaSocial  MediaKind = "social"
+	MediaEmbed   MediaKind = "embed"
+	MediaGraphic MediaKind = "graphic"
+)
+
+// Media is a video, audio, social post or other embedded content in the page.
+type Media struct {
+	Kind MediaKind
+
+	// Provider is the name of service that hosts the media, e.g. "YouTube".
+	// It's empty if the service is unknown.
+	Provider string
+
+	// Source is the URL of the media or its player. It's resolved against the page
+	// URL, so it's only absolute when the page URL is known or already absolute.
+	Source string
+
+	Title   string
+	Caption string
+
+	// Position is the number of paragraphs in the page before the media, while
+	// Before and After are the text of paragraphs around it.
+	Position int
+	Before   string
+	After    string
+
+	// Placeholder is the text of placeholder that left in the content tree,
+	// or empty if there are none.
+	Placeholder string
+}
+
+type mediaProvider struct {
+	host string
+	path string
+	name string
+	kind MediaKind
+}
+
+var mediaProviders = []mediaProvider{
+	{host: "youtube.com", name: "YouTube", kind: MediaVideo},
+	{host: "youtube-nocookie.com", name: "YouTube", kind: MediaVideo},
+	{host: "youtu.be", name: "YouTube", kind: MediaVideo},
+	{host: "vimeo.com", name: "Vimeo", kind: MediaVideo},
+	{host: "dailymotion.com", name: "Dailymotion", kind: MediaVideo},
+	{host: "dai.ly", name: "Dailymotion", kind: MediaVideo},
+	{host: "twitch.tv", name: "Twitch", kind: MediaVideo},
+	{host: "ted.com", name: "TED", kind: MediaVideo},
+	{host: "wistia.com", name: "Wistia", kind: MediaVideo},
+	{host: "wistia.net", name: "Wistia", kind: MediaVideo},
+	{host: "brightcove.net", name: "Brightcove", kind: MediaVideo},
+	{host: "soundcloud.com", name: "SoundCloud", kind: MediaAudio},
+	{host: "spotify.com", name: "Spotify", kind: MediaAudio},
+	{host: "podcasts.apple.com", name: "Apple Podcasts", kind: MediaAudio},
+	{host: "anchor.fm", name: "Anchor", kind: MediaAudio},
+	{host: "simplecast.com", name: "Simplecast", kind: MediaAudio},
+	{host: "libsyn.com", name: "Libsyn", kind: MediaAudio},
+	{host: "buzzsprout.com", name: "Buzzsprout", kind: MediaAudio},
+	{host: "podbean.com", name: "Podbean", kind: MediaAudio},
+	{host: "bandcamp.com", name: "Bandcamp", kind: MediaAudio},
+	{host: "mixcloud.com", name: "Mixcloud", kind: MediaAudio},
+	{host: "twitter.com", name: "Twitter", kind: MediaSocial},
+	{host: "x.com", name: "Twitter", kind: MediaSocial},
+	{host: "instagram.com", name: "Instagram", kind: MediaSocial},
+	{host: "facebook.com", path: "/plugins/post", name: "Facebook", kind: MediaSocial},
+	{host: "facebook.com", path: "/plugins/video", name: "Facebook", kind: MediaVideo},
+	{host: "facebook.com", name: "Facebook", kind: MediaEmbed},
+	{host: "tiktok.com", name: "TikTok", kind: MediaSocial},
+	{host: "reddit.com", name: "Reddit", kind: MediaSocial},
+	{host: "linkedin.com", name: "LinkedIn", kind: MediaSocial},
+	{host: "pinterest.com", name: "Pinterest", kind: MediaSocial},
+	{host: "threads.net", name: "Threads", kind: MediaSocial},
+	{host: "maps.google.com", name: "Google Maps", kind: MediaEmbed},
+	{host: "google.com", path: "/maps", name: "Google Maps", kind: MediaEmbed},
+	{host: "openstreetmap.org", name: "OpenStreetMap", kind: MediaEmbed},
+	{host: "dwcdn.net", name: "Datawrapper", kind: MediaEmbed},
+	{host: "flourish.studio", name: "Flourish", kind: MediaEmbed},
+	{host: "codepen.io", name: "CodePen", kind: MediaEmbed},
+	{host: "slideshare.net", name: "SlideShare", kind: MediaEmbed},
+}
+
+// socialEmbeds is the class names of the markup that used by social networks for
+// embedding post, which later turned into iframe by their script.
+var socialEmbeds = map[string]string{
+	"twitter-tweet":     "Twitter",
+	"twitter-video":     "Twitter",
+	"instagram-media":   "Instagram",
+	"tiktok-embed":      "TikTok",
+	"fb-post":           "Facebook",
+	"fb-video":          "Facebook",
+	"reddit-embed-bq":   "Reddit",
+	"reddit-card":       "Reddit",
+	"bluesky-embed":     "Bluesky",
+	"mastodon-embed":    "Mastodon",
+	"threads-post":      "Threads",
+	"text-post-media":   "Threads",
+	"linkedin-embed-bq": "LinkedIn",
+}
+
+// mediaContainers is the tags which removed by docCleaning that only contain the
+// media, so the placeholder can be put in their place.
+var mediaContainers = sliceToMap("audio", "embed", "figure", "iframe", "object", "picture", "svg", "video")
+
+// collectMedia records every media that embedded in the document. Since most of them
+// are removed by docCleaning, it must be done before the document is cleaned. If
+// MediaPlaceholders is enabled, the removed media are replaced by placeholders.
+func collectMedia(doc *html.Node, pageURL string, opts Options) []Media {
+	base, _ := nurl.Parse(pageURL)
+	if pageURL == "" {
+		base = nil
+	}
+
+	var medias []Media
+	var elements []*html.Node
+	var paragraphs []string
+
+	var walk func(*html.Node)
+	walk = func(node *html.Node) {
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type != html.ElementNode {
+				continue
+			}
+
+			if media, isMedia := mediaFromElement(child, base); isMedia {
+				media.Position = len(paragraphs)
+				medias = append(medias, media)
+				elements = append(elements, child)
+				continue
+			}
+
+			walk(child)
+
+			switch dom.TagName(child) {
+			case "p", "h1", "h2", "h3", "h4", "h5", "h6":
+				if text := trim(dom.TextContent(child)); text != "" {
+					paragraphs = append(paragraphs, text)
+				}
+			}
+		}
+	}
+	walk(doc)
+
+	for i := range medias {
+		if pos := medias[i].Position; pos > 0 {
+			medias[i].Before = paragraphs[pos-1]
+		}
+
+		if pos := medias[i].Position; pos < len(paragraphs) {
+			medias[i].After = paragraphs[pos]
+		}
+	}
+
+	if opts.MediaPlaceholders {
+		cleaningList, _ := cleaningStrategy(opts.ExcludeTables, opts.IncludeImages)
+		for i, element := range elements {
+			medias[i].Placeholder = insertMediaPlaceholder(element, medias[i], cleaningList)
+		}
+	}
+
+	return medias
+}
+
+// mediaFromElement returns the media that embedded by element.
+func mediaFromElement(element *html.Node, base *nurl.URL) (Media, bool) {
+	var media Media
+
+	tagName := dom.TagName(element)
+	switch tagName {
+	case "iframe", "embed":
+		// Skip the tracking pixels and hidden frames
+		if isHiddenMedia(element) {
+			return media, false
+		}
+
+		media.Kind = MediaEmbed
+		media.Source = strOr(
+			dom.GetAttribute(element, "src"),
+			dom.GetAttribute(element, "data-src"),
+			dom.GetAttribute(element, "data-lazy-src"))
+		if media.Source == "" || media.Source == "about:blank" {
+			return media, false
+		}
+
+	case "object":
+		media.Kind = MediaEmbed
+		media.Source = dom.GetAttribute(element, "data")
+		if param := dom.QuerySelector(element, `param[name="movie"], param[name="src"]`); param != nil {
+			media.Source = strOr(media.Source, dom.GetAttribute(param, "value"))
+		}
+		if embed := dom.QuerySelector(element, "embed[src]"); embed != nil {
+			media.Source = strOr(media.Source, dom.GetAttribute(embed, "src"))
+		}
+
+	case "video", "audio":
+		media.Kind = MediaVideo
+		if tagName == "audio" {
+			media.Kind = MediaAudio
+		}
+
+		media.Source = strOr(dom.GetAttribute(element, "src"), dom.GetAttribute(element, "data-src"))
+		if source := dom.QuerySelector(element, "source[src]"); source != nil {
+			media.Source = strOr(media.Source, dom.GetAttribute(source, "src"))
+		}
+
+	case "svg":
+		// Most inline svg are icons, so only keep the ones that look like a figure
+		if !isContentGraphic(element) {
+			return media, false
+		}
+
+		media.Kind = MediaGraphic
+		if title := dom.QuerySelector(element, "title"); title != nil {
+			media.Title = trim(dom.TextContent(title))
+		}
+
+	case "blockquote", "div":
+		for _, className := range strings.Fields(dom.GetAttribute(element, "class")) {
+			if provider, exist := socialEmbeds[className]; exist {
+				media.Kind = MediaSocial
+				media.Provider = provider
+				break
+			}
+		}
+
+		if media.Kind == "" {
+			return media, false
+		}
+
+		media.Source = strOr(
+			dom.GetAttribute(element, "data-instgrm-permalink"),
+			dom.GetAttribute(element, "data-href"),
+			dom.GetAttribute(element, "data-embed-url"),
+			dom.GetAttribute(element, "cite"))
+
+		// Twitter and most others put the permalink in the last link
+		if links := dom.QuerySelectorAll(element, "a[href]"); media.Source == "" && len(links) > 0 {
+			media.Source = dom.GetAttribute(links[len(links)-1], "href")
+		}
+
+		if paragraph := dom.QuerySelector(element, "p"); paragraph != nil {
+			media.Caption = trim(dom.TextContent(paragraph))
+		} else {
+			media.Caption = trim(dom.TextContent(element))
+		}
+
+	default:
+		return media, false
+	}
+
+	media.Source = createAbsoluteURL(trim(media.Source), base)
+	media.Title = strOr(media.Title,
+		trim(dom.GetAttribute(element, "title")),
+		trim(dom.GetAttribute(element, "aria-label")))
+
+	if media.Caption == "" {
+		media.Caption = mediaCaption(element)
+	}
+
+	// Use the provider to refine the kind
+	if media.Provider == "" {
+		if provider, exist := findMediaProvider(media.Source); exist {
+			media.Provider = provider.name
+			if media.Kind == MediaEmbed {
+				media.Kind = provider.kind
+			}
+		}
+	}
+
+	return media, true
+}
+
+// findMediaProvider returns the provider that hosts the URL.
+func findMediaProvider(url string) (mediaProvider, bool) {
+	parsedURL, err := nurl.Parse(url)
+	if err != nil || parsedURL.Hostname() == "" {
+		return mediaProvider{}, false
+	}
+
+	host := strings.ToLower(parsedURL.Hostname())
+	for _, provider := range mediaProviders {
+		if host != provider.host && !strings.HasSuffix(host, "."+provider.host) {
+			continue
+		}
+
+		if provider.path == "" || strings.HasPrefix(parsedURL.Path, provider.path) {
+			return provider, true
+		}
+	}
+
+	return mediaProvider{}, false
+}
+
+// isHiddenMedia checks if the media is hidden or too small to be seen.
+func isHiddenMedia(element *html.Node) bool {
+	if dom.HasAttribute(element, "hidden") {
+		return true
+	}
+
+	style := strings.ToLower(strings.Replace(dom.GetAttribute(element, "style"), " ", "", -1))
+	if strings.Contains(style, "display:none") || strings.Contains(style, "visibility:hidden") {
+		return true
+	}
+
+	width, hasWidth := mediaSize(element, "width")
+	height, hasHeight := mediaSize(element, "height")
+	return (hasWidth && width <= 1) || (hasHeight && height <= 1)
+}
+
+// isContentGraphic checks if the svg is a figure or chart instead of an icon.
+func isContentGraphic(element *html.Node) bool {
+	if dom.GetAttribute(element, "aria-hidden") == "true" {
+		return false
+	}
+
+	// Icons usually named as such, or taken from a sprite with <use>
+	if strings.Contains(strings.ToLower(dom.GetAttribute(element, "class")), "icon") {
+		return false
+	}
+
+	if children := dom.Children(element); len(children) == 1 && dom.TagName(children[0]) == "use" {
+		return false
+	}
+
+	for parent, depth := element.Parent, 0; parent != nil && depth < 3; parent, depth = parent.Parent, depth+1 {
+		switch dom.TagName(parent) {
+		case "a", "button":
+			return false
+		case "figure":
+			// Figure with image usually uses svg as decoration, e.g. zoom icon
+			return dom.QuerySelector(parent, "img") == nil
+		}
+	}
+
+	width, _ := mediaSize(element, "width")
+	height, _ := mediaSize(element, "height")
+	return width >= 100 && height >= 100
+}
+
+// mediaSize returns the size of media in pixel from the specified attribute.
+func mediaSize(element *html.Node, attrName string) (int, bool) {
+	value := strings.TrimSuffix(trim(dom.GetAttribute(element, attrName)), "px")
+	size, err := strconv.Atoi(value)
+	return size, err == nil
+}
+
+// mediaCaption returns the caption of figure that contains the element.
+func mediaCaption(element *html.Node) string {
+	for parent, depth := element.Parent, 0; parent != nil && depth < 3; parent, depth = parent.Parent, depth+1 {
+		if dom.TagName(parent) != "figure" {
+			continue
+		}
+
+		if caption := dom.QuerySelector(parent, "figcaption"); caption != nil {
+			return trim(dom.TextContent(caption))
+		}
+		break
+	}
+
+	return ""
+}
+
+// insertMediaPlaceholder puts placeholder for the media in the place of element, or
+// its container, if they will be removed by docCleaning. Returns the placeholder text.
+func insertMediaPlaceholder(element *html.Node, media Media, cleaningList map[string]struct{}) string {
+	if _, removed := cleaningList[dom.TagName(element)]; !removed {
+		return ""
+	}
+
+	// If the media is inside other removed elements like <aside> or <footer>,
+	// it's not part of the content so it doesn't need placeholder.
+	target := element
+	for parent := element.Parent; parent != nil; parent = parent.Parent {
+		tagName := dom.TagName(parent)
+		if _, removed := cleaningList[tagName]; !removed {
+			continue
+		}
+
+		if _, isContainer := mediaContainers[tagName]; !isContainer {
+			return ""
+		}
+		target = parent
+	}
+
+	if target.Parent == nil {
+		return ""
+	}
+
+	text := mediaPlaceholderText(media)
+	placeholder := dom.CreateTextNode(text)
+
+	// Paragraph can't be nested, so inside a paragraph only the text is inserted
+	if dom.TagName(target.Parent) != "p" {
+		paragraph := dom.CreateElement("p")
+		dom.AppendChild(paragraph, placeholder)
+		placeholder = paragraph
+	}
+
+	target.Parent.InsertBefore(placeholder, target)
+	return text
+}
+
+// mediaPlaceholderText returns the text for placeholder of media,
+// e.g. "[YouTube video: Title]".
+func mediaPlaceholderText(media Media) string {
+	var label string
+	switch media.Kind {
+	case MediaSocial:
+		label = "post"
+	default:
+		label = string(media.Kind)
+	}
+
+	if media.Provider != "" {
+		label = media.Provider + " " + label
+	} else {
+		label = strings.ToUpper(label[:1]) + label[1:]
+	}
+
+	// Use the host name for unknown provider, since the URL usually too long
+	detail := media.Title
+	if detail == "" && media.Provider == "" {
+		if parsedURL, err := nurl.Parse(media.Source); err == nil {
+			detail = parsedURL.Hostname()
+		}
+	}
+
+	if detail != "" {
+		return "[" + label + ": " + detail + "]"
+	}
+	return "[" + label + "]"
+}
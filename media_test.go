@@ -0,0 +1,110 @@
+// This file is part of go-trafilatura, Go package for extracting readable
+// content, comments and metadata from a web page. Source available in
+// <https://github.com/markusmobius/go-trafilatura>.
+// Copyright (C) 2021 Markus Mobius
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or (at your
+// option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+// or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License
+// for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package trafilatura
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Media(t *testing.T) {
+	rawHTML := `<html><body><article>
+		<p>First paragraph.</p>
+		<figure><iframe src="//www.youtube.com/embed/abc123" title="Demo video"></iframe>
+			<figcaption>A short demo.</figcaption></figure>
+		<p>Second paragraph.</p>
+		<p><iframe src="https://open.spotify.com/embed/episode/xyz"></iframe></p>
+		<video controls><source src="/media/clip.mp4" type="video/mp4"></video>
+		<blockquote class="twitter-tweet"><p>Hello world</p>&mdash; Someone
+			<a href="https://twitter.com/someone/status/1">June 1, 2021</a></blockquote>
+		<svg width="400" height="300"><title>Sales chart</title><rect width="10" height="10"/></svg>
+		<svg class="icon-share" width="400" height="400"><use href="#share"/></svg>
+		<iframe src="https://tracker.example.com/pixel" width="1" height="1"></iframe>
+		<p>Last paragraph.</p>
+		</article></body></html>`
+
+	doc := docFromStr(rawHTML)
+	media := collectMedia(doc, "https://example.org/post/", Options{})
+	assert.Len(t, media, 5)
+
+	assert.Equal(t, Media{
+		Kind:     MediaVideo,
+		Provider: "YouTube",
+		Source:   "https://www.youtube.com/embed/abc123",
+		Title:    "Demo video",
+		Caption:  "A short demo.",
+		Position: 1,
+		Before:   "First paragraph.",
+		After:    "Second paragraph.",
+	}, media[0])
+
+	assert.Equal(t, MediaAudio, media[1].Kind)
+	assert.Equal(t, "Spotify", media[1].Provider)
+	assert.Equal(t, 2, media[1].Position)
+
+	assert.Equal(t, MediaVideo, media[2].Kind)
+	assert.Equal(t, "", media[2].Provider)
+	assert.Equal(t, "https://example.org/media/clip.mp4", media[2].Source)
+
+	assert.Equal(t, MediaSocial, media[3].Kind)
+	assert.Equal(t, "Twitter", media[3].Provider)
+	assert.Equal(t, "https://twitter.com/someone/status/1", media[3].Source)
+	assert.Equal(t, "Hello world", media[3].Caption)
+
+	assert.Equal(t, MediaGraphic, media[4].Kind)
+	assert.Equal(t, "Sales chart", media[4].Title)
+	assert.Equal(t, "Last paragraph.", media[4].After)
+
+	// Without page URL, only the relative source is kept as it is
+	media = collectMedia(docFromStr(rawHTML), "", Options{})
+	assert.Equal(t, "/media/clip.mp4", media[2].Source)
+	assert.Equal(t, "https://twitter.com/someone/status/1", media[3].Source)
+
+	// Placeholders are only put for the media that removed by cleaning
+	doc = docFromStr(rawHTML)
+	media = collectMedia(doc, "https://example.org/post/", Options{MediaPlaceholders: true})
+	assert.Equal(t, "[YouTube video: Demo video]", media[0].Placeholder)
+	assert.Equal(t, "[Spotify audio]", media[1].Placeholder)
+	assert.Equal(t, "[Video: example.org]", media[2].Placeholder)
+	assert.Equal(t, "", media[3].Placeholder)
+	assert.Equal(t, "[Graphic: Sales chart]", media[4].Placeholder)
+
+	// Media in boilerplate doesn't have placeholder
+	doc = docFromStr(`<html><body><aside><iframe src="https://www.youtube.com/embed/x"></iframe></aside></body></html>`)
+	media = collectMedia(doc, "", Options{MediaPlaceholders: true})
+	assert.Len(t, media, 1)
+	assert.Equal(t, "", media[0].Placeholder)
+
+	// Placeholders are kept in the extracted content
+	paragraph := "<p>" + strings.Repeat("Lorem ipsum dolor sit amet, consectetur adipiscing elit. ", 5) + "</p>"
+	rawHTML = `<html><body><article>` + paragraph +
+		`<iframe src="https://player.vimeo.com/video/42"></iframe>` + paragraph + `</article></body></html>`
+
+	result, err := Extract(strings.NewReader(rawHTML), Options{NoFallback: true})
+	assert.Nil(t, err)
+	assert.Len(t, result.Media, 1)
+	assert.Equal(t, "Vimeo", result.Media[0].Provider)
+	assert.NotContains(t, result.ContentText, "[Vimeo video]")
+
+	result, err = Extract(strings.NewReader(rawHTML), Options{NoFallback: true, MediaPlaceholders: true})
+	assert.Nil(t, err)
+	assert.Contains(t, result.ContentText, "[Vimeo video]")
+}
@@ -87,6 +87,10 @@ func Test_Metadata_Authors(t *testing.T) {
 
 	metadata = testGetMetadataFromFile("simple/metadata-author-2.html")
 	assert.Equal(t, "Jean Sévillia", metadata.Author)
+
+	rawHTML = `<html><head><meta itemprop="author" content="Иван Петров"/></head><body></body></html>`
+	metadata = testGetMetadataFromHTML(rawHTML)
+	assert.Equal(t, "Иван Петров", metadata.Author)
 }
 
 func Test_Metadata_URLs(t *testing.T) {
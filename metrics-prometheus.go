@@ -0,0 +1,241 @@
+// This file is part of go-trafilatura, Go package for extracting readable
+// content, comments and metadata from a web page. Source available in
+// <https://github.com/markusmobius/go-trafilatura>.
+// Copyright (C) 2021 Markus Mobius
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or (at your
+// option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+// or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License
+// for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package trafilatura
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	durationBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+	bytesBuckets    = []float64{1e3, 1e4, 5e4, 1e5, 2.5e5, 5e5, 1e6, 5e6, 1e7}
+	charsBuckets    = []float64{10, 100, 250, 500, 1000, 2500, 5000, 10000, 50000}
+)
+
+// PrometheusMetrics is a MetricsHook that collects the measurements from extraction
+// pipeline and exports them in Prometheus text format. It's safe for concurrent use,
+// so a single instance can be shared by many extractions.
+type PrometheusMetrics struct {
+	sync.Mutex
+
+	outcomes  map[ExtractionOutcome]int64
+	fallbacks map[FallbackExtractor]int64
+	stages    map[ExtractionStage]*histogram
+	sizes     map[DocumentSize]*histogram
+}
+
+// NewPrometheusMetrics returns a new PrometheusMetrics.
+func NewPrometheusMetrics() *PrometheusMetrics {
+	return &PrometheusMetrics{
+		outcomes:  make(map[ExtractionOutcome]int64),
+		fallbacks: make(map[FallbackExtractor]int64),
+		stages:    make(map[ExtractionStage]*histogram),
+		sizes:     make(map[DocumentSize]*histogram),
+	}
+}
+
+// ObserveOutcome implements MetricsHook.
+func (pm *PrometheusMetrics) ObserveOutcome(outcome ExtractionOutcome) {
+	pm.Lock()
+	defer pm.Unlock()
+	pm.outcomes[outcome]++
+}
+
+// ObserveFallback implements MetricsHook.
+func (pm *PrometheusMetrics) ObserveFallback(extractor FallbackExtractor) {
+	pm.Lock()
+	defer pm.Unlock()
+	pm.fallbacks[extractor]++
+}
+
+// ObserveStageDuration implements MetricsHook.
+func (pm *PrometheusMetrics) ObserveStageDuration(stage ExtractionStage, duration time.Duration) {
+	pm.Lock()
+	defer pm.Unlock()
+
+	h, exist := pm.stages[stage]
+	if !exist {
+		h = newHistogram(durationBuckets)
+		pm.stages[stage] = h
+	}
+	h.observe(duration.Seconds())
+}
+
+// ObserveDocumentSize implements MetricsHook.
+func (pm *PrometheusMetrics) ObserveDocumentSize(kind DocumentSize, size int) {
+	pm.Lock()
+	defer pm.Unlock()
+
+	h, exist := pm.sizes[kind]
+	if !exist {
+		buckets := charsBuckets
+		if kind == SizeInputBytes {
+			buckets = bytesBuckets
+		}
+
+		h = newHistogram(buckets)
+		pm.sizes[kind] = h
+	}
+	h.observe(float64(size))
+}
+
+// WritePrometheus writes the collected metrics in Prometheus text format.
+func (pm *PrometheusMetrics) WritePrometheus(w io.Writer) error {
+	pm.Lock()
+	defer pm.Unlock()
+
+	buffer := bufio.NewWriter(w)
+
+	// Outcomes, the known outcomes are always printed to keep the series stable
+	outcomes := map[string]int64{}
+	for _, outcome := range []ExtractionOutcome{OutcomeSuccess, OutcomeTooShort, OutcomeWrongLanguage,
+		OutcomeDuplicate, OutcomeMissingMetadata, OutcomeTreeTooLarge, OutcomeInvalidDocument, OutcomeLimitExceeded,
+		OutcomePanic} {
+		outcomes[string(outcome)] = 0
+	}
+
+	for outcome, count := range pm.outcomes {
+		outcomes[string(outcome)] = count
+	}
+
+	fmt.Fprintln(buffer, "# HELP trafilatura_extractions_total Number of extractions by outcome.")
+	fmt.Fprintln(buffer, "# TYPE trafilatura_extractions_total counter")
+	for _, outcome := range sortedKeys(outcomes) {
+		fmt.Fprintf(buffer, "trafilatura_extractions_total{outcome=%q} %d\n", outcome, outcomes[outcome])
+	}
+
+	// Fallbacks
+	fallbacks := map[string]int64{}
+	for _, extractor := range []FallbackExtractor{FallbackReadability, FallbackDomDistiller, FallbackBaseline, FallbackCandidate} {
+		fallbacks[string(extractor)] = 0
+	}
+
+	for extractor, count := range pm.fallbacks {
+		fallbacks[string(extractor)] = count
+	}
+
+	fmt.Fprintln(buffer, "# HELP trafilatura_fallbacks_total Number of extractions that use result of fallback extractor.")
+	fmt.Fprintln(buffer, "# TYPE trafilatura_fallbacks_total counter")
+	for _, extractor := range sortedKeys(fallbacks) {
+		fmt.Fprintf(buffer, "trafilatura_fallbacks_total{extractor=%q} %d\n", extractor, fallbacks[extractor])
+	}
+
+	// Stage durations
+	var stages []ExtractionStage
+	for stage := range pm.stages {
+		stages = append(stages, stage)
+	}
+
+	sort.Slice(stages, func(i, j int) bool {
+		return stages[i] < stages[j]
+	})
+
+	fmt.Fprintln(buffer, "# HELP trafilatura_stage_duration_seconds Duration of each stage in extraction pipeline.")
+	fmt.Fprintln(buffer, "# TYPE trafilatura_stage_duration_seconds histogram")
+	for _, stage := range stages {
+		pm.stages[stage].write(buffer, "trafilatura_stage_duration_seconds", fmt.Sprintf("stage=%q", stage))
+	}
+
+	// Document sizes
+	sizeHelps := map[DocumentSize]string{
+		SizeInputBytes:    "Size of the original HTML document in bytes.",
+		SizeContentChars:  "Number of characters in extracted content.",
+		SizeCommentsChars: "Number of characters in extracted comments.",
+	}
+
+	for _, kind := range []DocumentSize{SizeInputBytes, SizeContentChars, SizeCommentsChars} {
+		h, exist := pm.sizes[kind]
+		if !exist {
+			continue
+		}
+
+		name := "trafilatura_document_" + string(kind)
+		fmt.Fprintf(buffer, "# HELP %s %s\n", name, sizeHelps[kind])
+		fmt.Fprintf(buffer, "# TYPE %s histogram\n", name)
+		h.write(buffer, name, "")
+	}
+
+	return buffer.Flush()
+}
+
+// histogram is cumulative histogram in the same way as Prometheus.
+type histogram struct {
+	buckets []float64
+	counts  []int64
+	count   int64
+	sum     float64
+}
+
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{
+		buckets: buckets,
+		counts:  make([]int64, len(buckets)),
+	}
+}
+
+func (h *histogram) observe(value float64) {
+	for i, bucket := range h.buckets {
+		if value <= bucket {
+			h.counts[i]++
+		}
+	}
+
+	h.count++
+	h.sum += value
+}
+
+func (h *histogram) write(w io.Writer, name string, labels string) {
+	withLabels := func(extra string) string {
+		switch {
+		case labels == "" && extra == "":
+			return ""
+		case labels == "":
+			return "{" + extra + "}"
+		case extra == "":
+			return "{" + labels + "}"
+		default:
+			return "{" + labels + "," + extra + "}"
+		}
+	}
+
+	for i, bucket := range h.buckets {
+		le := fmt.Sprintf("le=%q", strconv.FormatFloat(bucket, 'g', -1, 64))
+		fmt.Fprintf(w, "%s_bucket%s %d\n", name, withLabels(le), h.counts[i])
+	}
+
+	fmt.Fprintf(w, "%s_bucket%s %d\n", name, withLabels(`le="+Inf"`), h.count)
+	fmt.Fprintf(w, "%s_sum%s %s\n", name, withLabels(""), strconv.FormatFloat(h.sum, 'g', -1, 64))
+	fmt.Fprintf(w, "%s_count%s %d\n", name, withLabels(""), h.count)
+}
+
+func sortedKeys(m map[string]int64) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,131 @@
+// This file is part of go-trafilatura, Go package for extracting readable
+// content, comments and metadata from a web page. Source available in
+// <https://github.com/markusmobius/go-trafilatura>.
+// Copyright (C) 2021 Markus Mobius
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or (at your
+// option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+// or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License
+// for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package trafilatura
+
+import (
+	"io"
+	"time"
+)
+
+// ExtractionOutcome is the final outcome of an extraction.
+type ExtractionOutcome string
+
+const (
+	OutcomeSuccess         ExtractionOutcome = "success"
+	OutcomeTooShort        ExtractionOutcome = "too_short"
+	OutcomeWrongLanguage   ExtractionOutcome = "wrong_language"
+	OutcomeDuplicate       ExtractionOutcome = "duplicate"
+	OutcomeMissingMetadata ExtractionOutcome = "missing_metadata"
+	OutcomeTreeTooLarge    ExtractionOutcome = "tree_too_large"
+	OutcomeInvalidDocument ExtractionOutcome = "invalid_document"
+	OutcomeLimitExceeded   ExtractionOutcome = "limit_exceeded"
+	OutcomePanic           ExtractionOutcome = "panic"
+)
+
+// FallbackExtractor is the name of fallback extractor whose result is used
+// because the result of main extractor is not good enough.
+type FallbackExtractor string
+
+const (
+	FallbackReadability  FallbackExtractor = "readability"
+	FallbackDomDistiller FallbackExtractor = "dom-distiller"
+	FallbackBaseline     FallbackExtractor = "baseline"
+	FallbackCandidate    FallbackExtractor = "candidate"
+)
+
+// ExtractionStage is the stage in extraction pipeline.
+type ExtractionStage string
+
+const (
+	StageParsing        ExtractionStage = "parsing"
+	StageMetadata       ExtractionStage = "metadata"
+	StageCleaning       ExtractionStage = "cleaning"
+	StageComments       ExtractionStage = "comments"
+	StageContent        ExtractionStage = "content"
+	StageFallback       ExtractionStage = "fallback"
+	StagePostProcessing ExtractionStage = "post_processing"
+)
+
+// DocumentSize is the kind of size that measured from a document.
+type DocumentSize string
+
+const (
+	// SizeInputBytes is the size of original HTML in bytes. Only
+	// measured when extracting using `Extract`.
+	SizeInputBytes DocumentSize = "input_bytes"
+
+	// SizeContentChars is the number of characters in extracted content.
+	SizeContentChars DocumentSize = "content_chars"
+
+	// SizeCommentsChars is the number of characters in extracted comments.
+	SizeCommentsChars DocumentSize = "comments_chars"
+)
+
+// MetricsHook receives measurements from the extraction pipeline, which can be used
+// to monitor the extractor. The methods might be called concurrently by several
+// extractions, so the implementation must be safe for concurrent use.
+type MetricsHook interface {
+	// ObserveOutcome is called once for every extraction with its final outcome.
+	ObserveOutcome(outcome ExtractionOutcome)
+
+	// ObserveFallback is called when the result of fallback extractor is used.
+	ObserveFallback(extractor FallbackExtractor)
+
+	// ObserveStageDuration is called after a stage in extraction pipeline is finished.
+	ObserveStageDuration(stage ExtractionStage, duration time.Duration)
+
+	// ObserveDocumentSize is called with the size of the processed document.
+	ObserveDocumentSize(kind DocumentSize, size int)
+}
+
+func observeOutcome(opts Options, outcome ExtractionOutcome) {
+	if opts.Metrics != nil {
+		opts.Metrics.ObserveOutcome(outcome)
+	}
+}
+
+func observeFallback(opts Options, extractor FallbackExtractor) {
+	if opts.Metrics != nil {
+		opts.Metrics.ObserveFallback(extractor)
+	}
+}
+
+func observeStage(opts Options, stage ExtractionStage, start time.Time) {
+	if opts.Metrics != nil {
+		opts.Metrics.ObserveStageDuration(stage, time.Since(start))
+	}
+}
+
+func observeSize(opts Options, kind DocumentSize, size int) {
+	if opts.Metrics != nil {
+		opts.Metrics.ObserveDocumentSize(kind, size)
+	}
+}
+
+// countingReader is reader that counts the number of bytes that read from it.
+type countingReader struct {
+	Reader io.Reader
+	N      int
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.Reader.Read(p)
+	cr.N += n
+	return n, err
+}
@@ -0,0 +1,119 @@
+// This file is part of go-trafilatura, Go package for extracting readable
+// content, comments and metadata from a web page. Source available in
+// <https://github.com/markusmobius/go-trafilatura>.
+// Copyright (C) 2021 Markus Mobius
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or (at your
+// option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+// or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License
+// for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package trafilatura
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_PrometheusMetrics(t *testing.T) {
+	metrics := NewPrometheusMetrics()
+	opts := Options{NoFallback: true, Metrics: metrics}
+
+	// Successful extraction
+	paragraph := "<p>" + strings.Repeat("Lorem ipsum dolor sit amet, consectetur adipiscing elit. ", 10) + "</p>"
+	rawHTML := "<html><body><article>" + strings.Repeat(paragraph, 3) + "</article></body></html>"
+	_, err := Extract(strings.NewReader(rawHTML), opts)
+	assert.Nil(t, err)
+
+	// Content is too short
+	_, err = Extract(strings.NewReader("<html><body><p>a</p></body></html>"), opts)
+	assert.NotNil(t, err)
+
+	// Missing metadata
+	opts.HasEssentialMetadata = true
+	_, err = Extract(strings.NewReader(rawHTML), opts)
+	assert.NotNil(t, err)
+
+	// Fallback to baseline since the content is not found by main extractor
+	opts = Options{NoFallback: true, Metrics: metrics}
+	rawHTML = `<html><body><div class="footer"><p>` + strings.Repeat("Some text. ", 5) + `</p></div></body></html>`
+	_, err = Extract(strings.NewReader(rawHTML), opts)
+	assert.Nil(t, err)
+
+	buffer := bytes.NewBuffer(nil)
+	assert.Nil(t, metrics.WritePrometheus(buffer))
+	output := buffer.String()
+
+	assert.Contains(t, output, `trafilatura_extractions_total{outcome="success"} 2`)
+	assert.Contains(t, output, `trafilatura_extractions_total{outcome="too_short"} 1`)
+	assert.Contains(t, output, `trafilatura_extractions_total{outcome="missing_metadata"} 1`)
+	assert.Contains(t, output, `trafilatura_extractions_total{outcome="duplicate"} 0`)
+	assert.Contains(t, output, `trafilatura_fallbacks_total{extractor="baseline"} 1`)
+	assert.Contains(t, output, `trafilatura_stage_duration_seconds_count{stage="content"} 3`)
+	assert.Contains(t, output, `trafilatura_document_input_bytes_count 4`)
+	assert.Contains(t, output, `trafilatura_document_content_chars_bucket{le="+Inf"} 2`)
+}
+
+// panickyMetrics is metrics hook that panics once the specified stage finished.
+type panickyMetrics struct {
+	*PrometheusMetrics
+	stage ExtractionStage
+}
+
+func (pm panickyMetrics) ObserveStageDuration(stage ExtractionStage, duration time.Duration) {
+	if stage == pm.stage {
+		panic("something went wrong")
+	}
+	pm.PrometheusMetrics.ObserveStageDuration(stage, duration)
+}
+
+// panickyReader is reader that panics while the document is decoded.
+type panickyReader struct{}
+
+func (panickyReader) Read(p []byte) (int, error) {
+	panic("something went wrong")
+}
+
+func Test_PanicRecovery(t *testing.T) {
+	rawHTML := "<html><body><p>" + strings.Repeat("Lorem ipsum dolor sit amet. ", 20) + "</p></body></html>"
+
+	// Panic in the middle of extraction, and right after parsing
+	for _, stage := range []ExtractionStage{StageContent, StageParsing} {
+		metrics := panickyMetrics{NewPrometheusMetrics(), stage}
+		opts := Options{Metrics: metrics}
+
+		result, err := Extract(strings.NewReader(rawHTML), opts)
+		assert.Nil(t, result)
+
+		var panicErr *PanicError
+		assert.True(t, errors.As(err, &panicErr), stage)
+		if panicErr != nil {
+			assert.Equal(t, "something went wrong", panicErr.Value)
+			assert.NotEmpty(t, panicErr.Stack)
+		}
+
+		buffer := bytes.NewBuffer(nil)
+		assert.Nil(t, metrics.WritePrometheus(buffer))
+		assert.Contains(t, buffer.String(), `trafilatura_extractions_total{outcome="panic"} 1`)
+	}
+
+	// Panic while decoding the document
+	result, err := Extract(panickyReader{}, Options{})
+	assert.Nil(t, result)
+
+	var panicErr *PanicError
+	assert.True(t, errors.As(err, &panicErr))
+}
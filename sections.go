@@ -0,0 +1,171 @@
+// This file is part of go-trafilatura, Go package for extracting readable
+// content, comments and metadata from a web page. Source available in
+// <https://github.com/markusmobius/go-trafilatura>.
+// Copyright (C) 2021 Markus Mobius
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or (at your
+// option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+// or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License
+// for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package trafilatura
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/go-shiori/dom"
+	"golang.org/x/net/html"
+)
+
+// Section is a part of the extracted content that started by a heading.
+type Section struct {
+	// Level is the level of the heading, i.e. 1 for <h1> until 6 for <h6>. It's
+	// zero for the content before the first heading, which doesn't have title.
+	Level int
+
+	// Title is the text of the heading.
+	Title string
+
+	// Anchor is the unique slug of the title, which can be used as fragment to
+	// link the section. It's generated in the same way as headings on GitHub.
+	Anchor string
+
+	// Heading is the heading element inside the content node.
+	Heading *html.Node
+
+	// Nodes is the content elements between the heading and the next heading.
+	Nodes []*html.Node
+
+	// Children is the sub sections, i.e. sections with lower level heading.
+	Children []*Section
+}
+
+var sectionContainerTags = sliceToMap("article", "div", "main", "section")
+
+// Sections splits the extracted content into hierarchical sections using its headings.
+// Content before the first heading is returned as section with zero level.
+func (r *ExtractResult) Sections() []*Section {
+	if r == nil || r.ContentNode == nil {
+		return nil
+	}
+
+	var roots []*Section
+	var stack []*Section
+	var current *Section
+	anchors := make(map[string]int)
+
+	var walk func(*html.Node)
+	walk = func(parent *html.Node) {
+		for _, child := range dom.Children(parent) {
+			tagName := dom.TagName(child)
+			level := headingLevel(tagName)
+
+			switch {
+			case level > 0:
+				title := trim(dom.TextContent(child))
+				current = &Section{
+					Level:   level,
+					Title:   title,
+					Anchor:  uniqueAnchor(title, anchors),
+					Heading: child,
+				}
+
+				// Find the parent section, i.e. the last one with higher level heading
+				for len(stack) > 0 && stack[len(stack)-1].Level >= level {
+					stack = stack[:len(stack)-1]
+				}
+
+				if len(stack) == 0 {
+					roots = append(roots, current)
+				} else {
+					parentSection := stack[len(stack)-1]
+					parentSection.Children = append(parentSection.Children, current)
+				}
+				stack = append(stack, current)
+
+			case isSectionContainer(child):
+				walk(child)
+
+			default:
+				if current == nil {
+					current = &Section{}
+					roots = append(roots, current)
+				}
+				current.Nodes = append(current.Nodes, child)
+			}
+		}
+	}
+
+	walk(r.ContentNode)
+	return roots
+}
+
+// isSectionContainer checks if the element is a generic container that wraps headings,
+// so its children should be split into sections as well.
+func isSectionContainer(element *html.Node) bool {
+	if _, exist := sectionContainerTags[dom.TagName(element)]; !exist {
+		return false
+	}
+
+	return dom.QuerySelector(element, "h1,h2,h3,h4,h5,h6") != nil
+}
+
+// headingLevel returns the level of heading tag, or zero if it's not a heading.
+func headingLevel(tagName string) int {
+	if len(tagName) != 2 || tagName[0] != 'h' || tagName[1] < '1' || tagName[1] > '6' {
+		return 0
+	}
+	return int(tagName[1] - '0')
+}
+
+// uniqueAnchor creates slug for the title and adds numeric suffix to
+// make it unique within the document.
+func uniqueAnchor(title string, anchors map[string]int) string {
+	anchor := slugify(title)
+	if anchor == "" {
+		anchor = "section"
+	}
+
+	count, exist := anchors[anchor]
+	anchors[anchor] = count + 1
+	if !exist {
+		return anchor
+	}
+
+	// The suffixed anchor might be used by another title, e.g. "A 1"
+	unique := anchor + "-" + strconv.Itoa(count)
+	for anchors[unique] > 0 {
+		count++
+		unique = anchor + "-" + strconv.Itoa(count)
+	}
+
+	anchors[anchor] = count + 1
+	anchors[unique]++
+	return unique
+}
+
+// slugify converts the text into slug by lowercasing it, replacing the spaces with
+// dash and removing punctuations, following how GitHub creates heading anchors.
+func slugify(text string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(trim(text)) {
+		switch {
+		case r == ' ':
+			sb.WriteRune('-')
+		case r == '-' || r == '_',
+			unicode.IsLetter(r), unicode.IsNumber(r), unicode.Is(unicode.Mn, r):
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
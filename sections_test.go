@@ -0,0 +1,101 @@
+// This file is part of go-trafilatura, Go package for extracting readable
+// content, comments and metadata from a web page. Source available in
+// <https://github.com/markusmobius/go-trafilatura>.
+// Copyright (C) 2021 Markus Mobius
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or (at your
+// option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+// or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License
+// for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package trafilatura
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-shiori/dom"
+	"github.com/markusmobius/go-trafilatura/internal/etree"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Sections(t *testing.T) {
+	// Empty result
+	assert.Nil(t, (*ExtractResult)(nil).Sections())
+	assert.Nil(t, (&ExtractResult{}).Sections())
+
+	// Hierarchical sections
+	content := etree.FromString(`<article>
+		<p>Introduction.</p>
+		<h1>Getting Started</h1>
+		<p>First steps.</p>
+		<h2>Install</h2>
+		<p>Run the installer.</p>
+		<ul><li>Step one</li></ul>
+		<div><h2>Install</h2><p>Again?</p></div>
+		<h3>Details &amp; Notes!</h3>
+		<p>Nested details.</p>
+		<h1>FAQ</h1>
+		<p>Questions.</p>
+	</article>`)
+
+	sections := (&ExtractResult{ContentNode: content}).Sections()
+	assert.Len(t, sections, 3)
+
+	intro := sections[0]
+	assert.Equal(t, 0, intro.Level)
+	assert.Equal(t, "", intro.Title)
+	assert.Nil(t, intro.Heading)
+	assert.Len(t, intro.Nodes, 1)
+	assert.Equal(t, "Introduction.", dom.TextContent(intro.Nodes[0]))
+
+	started := sections[1]
+	assert.Equal(t, 1, started.Level)
+	assert.Equal(t, "Getting Started", started.Title)
+	assert.Equal(t, "getting-started", started.Anchor)
+	assert.Len(t, started.Nodes, 1)
+	assert.Len(t, started.Children, 2)
+
+	install := started.Children[0]
+	assert.Equal(t, "install", install.Anchor)
+	assert.Len(t, install.Nodes, 2)
+	assert.Equal(t, "ul", dom.TagName(install.Nodes[1]))
+
+	installAgain := started.Children[1]
+	assert.Equal(t, "install-1", installAgain.Anchor)
+	assert.Equal(t, "Again?", dom.TextContent(installAgain.Nodes[0]))
+	assert.Len(t, installAgain.Children, 1)
+	assert.Equal(t, 3, installAgain.Children[0].Level)
+	assert.Equal(t, "details--notes", installAgain.Children[0].Anchor)
+
+	assert.Equal(t, "FAQ", sections[2].Title)
+	assert.Equal(t, "faq", sections[2].Anchor)
+	assert.Empty(t, sections[2].Children)
+
+	// Extraction result
+	rawHTML := `<html><body><article><h2>Überblick</h2><p>` + strings.Repeat("Text des Artikels. ", 5) + `</p>` +
+		`<h2>Zweiter Teil</h2><p>` + strings.Repeat("Mehr Text. ", 5) + `</p></article></body></html>`
+	result, err := Extract(strings.NewReader(rawHTML), zeroOpts)
+	assert.Nil(t, err)
+
+	sections = result.Sections()
+	assert.Len(t, sections, 2)
+	assert.Equal(t, "überblick", sections[0].Anchor)
+	assert.Equal(t, "zweiter-teil", sections[1].Anchor)
+
+	// Suffixed anchors don't collide with the other titles
+	anchors := make(map[string]int)
+	var uniques []string
+	for _, title := range []string{"A 1", "A", "A", "A", "A 2", "A 1"} {
+		uniques = append(uniques, uniqueAnchor(title, anchors))
+	}
+	assert.Equal(t, []string{"a-1", "a", "a-2", "a-3", "a-2-1", "a-1-1"}, uniques)
+}
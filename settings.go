@@ -63,6 +63,13 @@ var formatTagCatalog = sliceToMap(
 	"samp", "tt", "var", "sub", "sup",
 )
 
+// listItemTagCatalog is the elements that kept inside list items along with
+// formatTagCatalog. Other elements are unwrapped.
+var listItemTagCatalog = sliceToMap(
+	"a", "blockquote", "code", "del", "h1", "h2", "h3", "h4", "h5", "h6",
+	"p", "pre", "q", "s",
+)
+
 var tagsToSanitize = sliceToMap(
 	"aside", "audio", "button", "fieldset", "figure", "footer", "iframe",
 	"img", "image", "input", "label", "link", "nav", "noindex", "noscript",
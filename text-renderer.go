@@ -0,0 +1,370 @@
+// This file is part of go-trafilatura, Go package for extracting readable
+// content, comments and metadata from a web page. Source available in
+// <https://github.com/markusmobius/go-trafilatura>.
+// Copyright (C) 2021 Markus Mobius
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or (at your
+// option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+// or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License
+// for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package trafilatura
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/go-shiori/dom"
+	"github.com/markusmobius/go-trafilatura/internal/etree"
+	"golang.org/x/net/html"
+)
+
+// TextOptions is configuration for rendering the extracted content as plain text.
+type TextOptions struct {
+	// Width is the max width of each line. Longer lines are wrapped at word boundary,
+	// except for code blocks and tables which are kept as it is. If it's zero or
+	// negative, the lines are not wrapped.
+	Width int
+}
+
+var textBlockTags = sliceToMap(
+	"address", "article", "aside", "body", "center", "dd", "details", "div", "dl", "dt",
+	"figcaption", "figure", "footer", "form", "h1", "h2", "h3", "h4", "h5", "h6", "header",
+	"hr", "html", "li", "main", "nav", "ol", "p", "section", "summary", "table", "ul",
+	"blockquote", "pre",
+)
+
+// RenderText renders the extracted content (e.g. `ExtractResult.ContentNode`) as plain
+// text. Paragraphs, headings and other blocks are separated by blank line, list items
+// are prefixed by bullet or number, quotes are indented, tables are aligned in columns
+// and code blocks are kept verbatim.
+func RenderText(node *html.Node, opts TextOptions) string {
+	if node == nil {
+		return ""
+	}
+
+	var blocks []string
+	if _, isBlock := textBlockTags[dom.TagName(node)]; isBlock {
+		blocks = renderTextBlock(node, opts.Width)
+	} else {
+		blocks = renderTextBlocks(node, opts.Width)
+	}
+
+	return strings.Join(blocks, "\n\n")
+}
+
+// renderTextBlocks renders the children of node as list of text blocks.
+func renderTextBlocks(node *html.Node, width int) []string {
+	var blocks []string
+	var inline strings.Builder
+
+	flush := func() {
+		if block := renderTextInline(inline.String(), width); block != "" {
+			blocks = append(blocks, block)
+		}
+		inline.Reset()
+	}
+
+	var walk func(*html.Node)
+	walk = func(parent *html.Node) {
+		for child := parent.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type == html.TextNode {
+				inline.WriteString(child.Data)
+				continue
+			}
+
+			if child.Type != html.ElementNode {
+				continue
+			}
+
+			tagName := dom.TagName(child)
+			if _, isBlock := textBlockTags[tagName]; isBlock {
+				flush()
+				blocks = append(blocks, renderTextBlock(child, width)...)
+				continue
+			}
+
+			switch tagName {
+			case "br":
+				inline.WriteString("\n")
+			case "q":
+				inline.WriteString(`"`)
+				walk(child)
+				inline.WriteString(`"`)
+			default:
+				walk(child)
+			}
+		}
+	}
+
+	walk(node)
+	flush()
+	return blocks
+}
+
+// renderTextBlock renders a block element as list of text blocks.
+func renderTextBlock(element *html.Node, width int) []string {
+	var block string
+
+	switch dom.TagName(element) {
+	case "pre":
+		block = codeText(element)
+	case "hr":
+		block = "* * *"
+	case "ul", "ol":
+		block = renderTextList(element, width)
+	case "dl":
+		block = renderTextDefinitions(element, width)
+	case "table":
+		block = renderTextTable(element)
+	case "blockquote":
+		quote := renderTextBlocks(element, subWidth(width, 4))
+		block = indentText(strings.Join(quote, "\n\n"), "    ", "    ")
+	default:
+		return renderTextBlocks(element, width)
+	}
+
+	if strings.TrimSpace(block) == "" {
+		return nil
+	}
+
+	return []string{block}
+}
+
+// renderTextInline collapses the whitespaces in inline text then wraps it.
+// Explicit line breaks (from <br>) are kept.
+func renderTextInline(text string, width int) string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = trim(line); line != "" {
+			lines = append(lines, wrapText(line, width))
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// renderTextList renders <ul> and <ol> with bullet or number for each item.
+func renderTextList(list *html.Node, width int) string {
+	isOrdered := dom.TagName(list) == "ol"
+	isReversed := dom.HasAttribute(list, "reversed")
+
+	number := 1
+	if start, err := strconv.Atoi(trim(dom.GetAttribute(list, "start"))); err == nil {
+		number = start
+	} else if isReversed {
+		number = countListItems(list)
+	}
+
+	var items []string
+	for _, item := range dom.Children(list) {
+		if dom.TagName(item) != "li" {
+			continue
+		}
+
+		marker := "- "
+		if isOrdered {
+			if value, err := strconv.Atoi(trim(dom.GetAttribute(item, "value"))); err == nil {
+				number = value
+			}
+			marker = strconv.Itoa(number) + ". "
+			if isReversed {
+				number--
+			} else {
+				number++
+			}
+		}
+
+		content := renderTextBlocks(item, subWidth(width, len(marker)))
+		if len(content) == 0 {
+			continue
+		}
+
+		text := strings.Join(content, "\n")
+		items = append(items, indentText(text, marker, strings.Repeat(" ", len(marker))))
+	}
+
+	return strings.Join(items, "\n")
+}
+
+// countListItems counts the direct <li> children of the list.
+func countListItems(list *html.Node) int {
+	var count int
+	for _, item := range dom.Children(list) {
+		if dom.TagName(item) == "li" {
+			count++
+		}
+	}
+	return count
+}
+
+// renderTextDefinitions renders <dl> with the definitions indented below its term.
+func renderTextDefinitions(list *html.Node, width int) string {
+	var items []string
+	for _, item := range dom.Children(list) {
+		switch dom.TagName(item) {
+		case "dt":
+			if content := renderTextBlocks(item, width); len(content) > 0 {
+				items = append(items, strings.Join(content, "\n"))
+			}
+		case "dd":
+			if content := renderTextBlocks(item, subWidth(width, 4)); len(content) > 0 {
+				items = append(items, indentText(strings.Join(content, "\n"), "    ", "    "))
+			}
+		}
+	}
+
+	return strings.Join(items, "\n")
+}
+
+// renderTextTable renders the table with its cells aligned in columns. If the
+// first row only contains header cells, it will be separated from the other rows.
+func renderTextTable(table *html.Node) string {
+	var rows [][]string
+	var columnWidths []int
+	var hasHeader bool
+
+	for i, tr := range etree.Iter(table, "tr") {
+		var cells []string
+		isHeader := true
+		for _, cell := range dom.Children(tr) {
+			tagName := dom.TagName(cell)
+			if tagName != "td" && tagName != "th" {
+				continue
+			}
+
+			if tagName == "td" {
+				isHeader = false
+			}
+
+			text := strings.Join(renderTextBlocks(cell, 0), " ")
+			text = strings.ReplaceAll(text, "\n", " ")
+			cells = append(cells, text)
+
+			column := len(cells) - 1
+			if column >= len(columnWidths) {
+				columnWidths = append(columnWidths, 0)
+			}
+
+			if cellWidth := textWidth(text); cellWidth > columnWidths[column] {
+				columnWidths[column] = cellWidth
+			}
+		}
+
+		if len(cells) == 0 {
+			continue
+		}
+
+		if i == 0 && isHeader {
+			hasHeader = true
+		}
+
+		rows = append(rows, cells)
+	}
+
+	var lines []string
+	for i, row := range rows {
+		cells := make([]string, len(row))
+		for column, cell := range row {
+			padding := columnWidths[column] - textWidth(cell)
+			cells[column] = cell + strings.Repeat(" ", padding)
+		}
+		lines = append(lines, strings.TrimRight(strings.Join(cells, " | "), " "))
+
+		if i == 0 && hasHeader {
+			separators := make([]string, len(columnWidths))
+			for column, columnWidth := range columnWidths {
+				separators[column] = strings.Repeat("-", columnWidth)
+			}
+			lines = append(lines, strings.Join(separators, "-+-"))
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// wrapText wraps a single line of text at the word boundary, so each
+// line is not wider than the specified width if possible.
+func wrapText(text string, width int) string {
+	if width <= 0 || textWidth(text) <= width {
+		return text
+	}
+
+	var lines []string
+	var line string
+	var lineWidth int
+
+	for _, word := range strings.Fields(text) {
+		wordWidth := textWidth(word)
+		switch {
+		case line == "":
+			line, lineWidth = word, wordWidth
+		case lineWidth+1+wordWidth <= width:
+			line += " " + word
+			lineWidth += 1 + wordWidth
+		default:
+			lines = append(lines, line)
+			line, lineWidth = word, wordWidth
+		}
+	}
+
+	if line != "" {
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// textWidth returns the display width of text, where Chinese, Japanese
+// and Korean characters are twice as wide as the others.
+func textWidth(text string) int {
+	var width int
+	for _, r := range text {
+		if isCJK(r) {
+			width += 2
+		} else {
+			width++
+		}
+	}
+	return width
+}
+
+// indentText prefixes the first line with first prefix and the other lines with
+// other prefix. Empty lines are kept empty.
+func indentText(text string, firstPrefix string, otherPrefix string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		switch {
+		case i == 0:
+			lines[i] = firstPrefix + line
+		case line != "":
+			lines[i] = otherPrefix + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// subWidth returns the width that left after indentation. There is always
+// some minimum width, so deeply nested content is not wrapped every word.
+func subWidth(width int, indent int) int {
+	if width <= 0 {
+		return width
+	}
+
+	switch {
+	case width-indent >= 20:
+		return width - indent
+	case width < 20:
+		return width
+	default:
+		return 20
+	}
+}
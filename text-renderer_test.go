@@ -0,0 +1,73 @@
+// This file is part of go-trafilatura, Go package for extracting readable
+// content, comments and metadata from a web page. Source available in
+// <https://github.com/markusmobius/go-trafilatura>.
+// Copyright (C) 2021 Markus Mobius
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or (at your
+// option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+// or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License
+// for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package trafilatura
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/markusmobius/go-trafilatura/internal/etree"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RenderText(t *testing.T) {
+	// Helper function
+	render := func(rawHTML string, width int) string {
+		return RenderText(etree.FromString(rawHTML), TextOptions{Width: width})
+	}
+
+	// Blocks are separated by blank line, line breaks are kept
+	assert.Equal(t, "Title\n\nFirst paragraph.\n\nSecond\nline with bold text.",
+		render(`<div><h1>Title</h1><p>First   paragraph.</p><p>Second<br>line with <b>bold</b> text.</p></div>`, 0))
+
+	// Lists with bullets and numbers, including the nested ones
+	assert.Equal(t, "- One\n- Two\n  3. Nested\n  4. Nested again\n- Three",
+		render(`<ul><li>One</li><li>Two<ol start="3"><li>Nested</li><li>Nested again</li></ol></li><li>Three</li></ul>`, 0))
+	assert.Equal(t, "2. Two\n1. One",
+		render(`<ol reversed><li>Two</li><li>One</li></ol>`, 0))
+	assert.Equal(t, "Term\n    Definition",
+		render(`<dl><dt>Term</dt><dd>Definition</dd></dl>`, 0))
+
+	// Indented quotes and verbatim code
+	assert.Equal(t, "Someone said:\n\n    To be or not to be.\n\n    That is the question.",
+		render(`<div><p>Someone said:</p><blockquote><p>To be or not to be.</p><p>That is the question.</p></blockquote></div>`, 0))
+	assert.Equal(t, "Code:\n\nif x {\n    y()\n}",
+		render("<div><p>Code:</p><pre><code>if x {\n    y()\n}</code></pre></div>", 10))
+
+	// Aligned tables
+	assert.Equal(t, "Name  | Score\n------+------\nAlice | 10\nBob   | 7",
+		render(`<table><tr><th>Name</th><th>Score</th></tr><tr><td>Alice</td><td>10</td></tr><tr><td>Bob</td><td>7</td></tr></table>`, 0))
+	assert.Equal(t, "東京 | Tokyo\nA    | B",
+		render(`<table><tr><td>東京</td><td>Tokyo</td></tr><tr><td>A</td><td>B</td></tr></table>`, 0))
+
+	// Line wrapping, including the list items
+	paragraph := strings.Repeat("lorem ipsum ", 5)
+	assert.Equal(t, "lorem ipsum lorem ipsum\nlorem ipsum lorem ipsum\nlorem ipsum",
+		render("<p>"+paragraph+"</p>", 25))
+	assert.Equal(t, "- lorem ipsum lorem ipsum\n  lorem ipsum lorem ipsum\n  lorem ipsum",
+		render("<ul><li>"+paragraph+"</li></ul>", 25))
+
+	// Extraction result
+	rawHTML := `<html><body><article><h2>Heading</h2><p>` + paragraph + `</p><ul><li>Item one</li><li>Item two</li></ul></article></body></html>`
+	result, err := Extract(strings.NewReader(rawHTML), zeroOpts)
+	assert.Nil(t, err)
+	assert.Equal(t, "Heading\n\n"+strings.TrimSpace(paragraph)+"\n\n- Item one\n- Item two",
+		RenderText(result.ContentNode, TextOptions{}))
+	assert.Equal(t, "", RenderText(nil, TextOptions{}))
+}
@@ -65,15 +65,16 @@ func Test_Trim(t *testing.T) {
 	assert.Equal(t, "Test", trim("	Test  "))
 	assert.Equal(t, "Test Test", trim("\t\tTest  Test\r\n"))
 
+	opts := Options{rxBoilerplate: compileBoilerplateFilter(defaultBoilerplatePhrases, "")}
 	elem := etree.Element("body")
 	etree.SetText(elem, "Test Text")
-	assert.False(t, textFilter(elem))
+	assert.False(t, textFilter(elem, opts))
 
 	etree.SetText(elem, "Instagram")
-	assert.True(t, textFilter(elem))
+	assert.True(t, textFilter(elem, opts))
 
 	etree.SetText(elem, "\t\t")
-	assert.True(t, textFilter(elem))
+	assert.True(t, textFilter(elem, opts))
 }
 
 func Test_ExoticTags(t *testing.T) {
@@ -115,6 +116,43 @@ func Test_ExoticTags(t *testing.T) {
 	assert.Equal(t, 3, strings.Count(strResult, "List item"))
 	assert.Contains(t, strResult, "Description")
 
+	// Nested lists
+	lists = etree.FromString(`
+	<ol start="3" type="a">
+		<li>First <b>item</b>
+			<ul>
+				<li>Nested one</li>
+				<li>Nested two<ol reversed><li>Deep</li></ol></li>
+			</ul>
+			after nested
+		</li>
+		<li value="7">
+			<div><p>Item with <a href="/docs">a link</a> inside.</p></div>
+			<pre><code>x := 1</code></pre>
+		</li>
+		<li><span>Text in span</span> and <div>text in div</div></li>
+	</ol>`)
+
+	handledLists = handleLists(lists, nil, zeroOpts)
+	assert.Equal(t, `<ol start="3" type="a">`+
+		`<li>First <b>item</b> <ul><li>Nested one</li><li>Nested two<ol reversed=""><li>Deep</li></ol></li></ul> after nested</li>`+
+		`<li value="7"><p>Item with <a href="/docs">a link</a> inside.</p> <pre><code>x := 1</code></pre></li>`+
+		`<li>Text in span and text in div</li>`+
+		`</ol>`, etree.ToString(handledLists))
+
+	// Definition lists
+	lists = etree.FromString(`
+	<dl>
+		<div><dt>Term A</dt><dd>Definition A1</dd><dd>Definition A2 <ul><li>Detail</li></ul></dd></div>
+		<dt>Term B</dt><dd><p>Definition B</p></dd>
+	</dl>`)
+
+	handledLists = handleLists(lists, nil, zeroOpts)
+	assert.Equal(t, `<dl>`+
+		`<dt>Term A</dt><dd>Definition A1</dd><dd>Definition A2 <ul><li>Detail</li></ul></dd>`+
+		`<dt>Term B</dt><dd><p>Definition B</p></dd>`+
+		`</dl>`, etree.ToString(handledLists))
+
 	// HTML5: <details>
 	opts := zeroOpts
 	opts.NoFallback = true
@@ -250,6 +288,69 @@ func Test_Formatting(t *testing.T) {
 	assert.Contains(t, fnHtml(result), `<a href="test.html">link</a>`)
 }
 
+func Test_CodeBlocks(t *testing.T) {
+	opts := zeroOpts
+	opts.NoFallback = true
+
+	// Block with language hint and indentation
+	htmlString := `<html><body><article>
+	<p>Here is how to print a <code>greeting</code> in Go:</p>
+	<pre class="chroma"><code class="language-go">func main() {
+	if ok {
+		fmt.Println("Hello,   world")
+	}
+}
+</code></pre>
+	<p>And the same in shell:</p>
+	<div data-lang="Bash"><pre>echo "Hello"<br>exit 0</pre></div>
+	</article></body></html>`
+
+	result, err := Extract(strings.NewReader(htmlString), opts)
+	assert.Nil(t, err)
+
+	goCode := "func main() {\n\tif ok {\n\t\tfmt.Println(\"Hello,   world\")\n\t}\n}"
+	assert.Equal(t, []CodeBlock{
+		{Language: "go", Code: goCode},
+		{Language: "bash", Code: "echo \"Hello\"\nexit 0"},
+	}, result.CodeBlocks)
+
+	contentHTML := etree.ToString(result.ContentNode)
+	assert.Contains(t, contentHTML, "<p>Here is how to print a <code>greeting</code> in Go:</p>")
+	assert.Contains(t, contentHTML, `<pre><code class="language-go">func main() {`)
+	assert.Contains(t, contentHTML, "<pre><code class=\"language-bash\">echo &#34;Hello&#34;\nexit 0</code></pre>")
+	assert.Contains(t, result.ContentText, "Here is how to print a greeting in Go:\n"+goCode+"\nAnd the same in shell:")
+
+	// Code outside of <pre>: single line is inline, multiple lines is block
+	htmlString = `<html><body><article>
+	<p>First paragraph of the article.</p>
+	<code>inline   code</code>
+	<code>line one
+  line two</code>
+	</article></body></html>`
+
+	result, err = Extract(strings.NewReader(htmlString), opts)
+	assert.Nil(t, err)
+	contentHTML = etree.ToString(result.ContentNode)
+	assert.Contains(t, contentHTML, "<p><code>inline code</code></p>")
+	assert.Contains(t, contentHTML, "<pre><code>line one\n  line two</code></pre>")
+	assert.Equal(t, []CodeBlock{{Code: "line one\n  line two"}}, result.CodeBlocks)
+
+	// Code block inside quote
+	quote := etree.FromString("<blockquote><p>Quoted</p><pre><code class=\"lang-py\">if x:\n    pass</code></pre></blockquote>")
+	handledQuote := handleQuotes(quote, nil, zeroOpts)
+	assert.Equal(t, "<blockquote><p>Quoted</p><pre><code class=\"language-py\">if x:\n    pass</code></pre></blockquote>",
+		etree.ToString(handledQuote))
+
+	// Whitespaces are collapsed except in code blocks
+	content := etree.FromString("<div><p>Some   text\n here</p><pre>a  b\n  c</pre><p>after</p></div>")
+	assert.Equal(t, "Some text here\na  b\n  c\nafter", contentText(content))
+
+	// Text after empty element is kept when it's inside code
+	doc := docFromStr(`<html><body><pre><code><span class="hl"></span>services:</code></pre></body></html>`)
+	pruneHTML(doc)
+	assert.Equal(t, "services:", dom.TextContent(dom.QuerySelector(doc, "code")))
+}
+
 func Test_Baseline(t *testing.T) {
 	// Blank document
 	doc := docFromStr("")
@@ -354,6 +455,101 @@ func Test_Filters(t *testing.T) {
 	opts.TargetLanguage = "de"
 	doc = docFromStr(`<html><head><meta http-equiv="content-language" content="en"></head><body></body></html>`)
 	assert.False(t, checkHtmlLanguage(doc, opts))
+
+	// Only primary subtag of BCP 47 language tag is used, case insensitively
+	opts.TargetLanguage = "zh"
+	doc = docFromStr(`<html lang="ZH-Hant-TW"><body></body></html>`)
+	assert.True(t, checkHtmlLanguage(doc, opts))
+
+	opts.TargetLanguage = "fi"
+	doc = docFromStr(`<html lang="fil"><body></body></html>`)
+	assert.False(t, checkHtmlLanguage(doc, opts))
+}
+
+func Test_MultiScript(t *testing.T) {
+	// Each page has paragraphs in <p>, <div> and <li>, surrounded by navigation and footer
+	pages := map[string]struct {
+		mustContain    []string
+		mustNotContain []string
+	}{
+		"ru.html": {
+			[]string{"Первые испытания прошли в нескольких сёлах", "В следующем году команда планирует"},
+			[]string{"Политика", "Все права защищены"}},
+		"ar.html": {
+			[]string{"وتخطط إدارة المكتبة لتنظيم ورش عمل", "ويأمل السكان أن تسهم المكتبة الجديدة"},
+			[]string{"اقتصاد", "جميع الحقوق محفوظة"}},
+		"he.html": {
+			[]string{"החוקרים מדגישים שהשיטה אינה תחליף", "בשנה הבאה מתוכנן ניסוי רחב יותר"},
+			[]string{"פוליטיקה", "כל הזכויות שמורות"}},
+		"hi.html": {
+			[]string{"किसानों का कहना है कि शुरुआत में लागत", "अब आसपास के कई गांवों के किसान"},
+			[]string{"राजनीति", "सर्वाधिकार सुरक्षित"}},
+		"el.html": {
+			[]string{"Η διευθύντρια του μουσείου δήλωσε", "Η είσοδος θα είναι δωρεάν"},
+			[]string{"Αθλητισμός", "Με την επιφύλαξη παντός δικαιώματος"}},
+		"zh.html": {
+			[]string{"市民普遍表示支持", "交通部门表示，将在三个月后评估实施效果"},
+			[]string{"国际", "版权所有"}},
+		"ja.html": {
+			[]string{"担当の先生は、失敗から学ぶ姿勢", "市の教育委員会は、来年度から"},
+			[]string{"スポーツ", "無断転載を禁じます"}},
+		"ko.html": {
+			[]string{"도서관 측은 야간 이용자를 위해", "시범 운영 결과가 좋으면"},
+			[]string{"스포츠", "무단 전재 및 재배포 금지"}},
+	}
+
+	opts := Options{NoFallback: true, Config: DefaultConfig()}
+	for name, page := range pages {
+		f, err := os.Open(filepath.Join("test-files", "multiscript", name))
+		assert.Nil(t, err)
+
+		result, err := Extract(f, opts)
+		f.Close()
+		if !assert.Nil(t, err, name) {
+			continue
+		}
+
+		for _, snippet := range page.mustContain {
+			assert.Contains(t, result.ContentText, snippet, name)
+		}
+
+		for _, snippet := range page.mustNotContain {
+			assert.NotContains(t, result.ContentText, snippet, name)
+		}
+	}
+
+	// CJK characters are weighted when compared with size thresholds
+	cfg := DefaultConfig()
+	assert.Equal(t, 5, weightedLength("hello", cfg))
+	assert.Equal(t, 10, weightedLength("新しい規則", cfg))
+	assert.Equal(t, 9, weightedLength("시범 운영", cfg))
+
+	cfg.CJKCharWeight = 0
+	assert.Equal(t, 5, weightedLength("新しい規則", cfg))
+}
+
+func Test_ExtractDocumentUntouched(t *testing.T) {
+	// The original document is used as backup for fallback extractors,
+	// so it must be kept untouched by extraction.
+	for _, url := range []string{
+		"https://die-partei.net/luebeck/2012/05/31/das-ministerium-fur-club-kultur-informiert/",
+		"http://exotic_tags",
+	} {
+		doc := parseMockFile(rwMockFiles, url)
+		original := dom.OuterHTML(doc)
+
+		result, err := ExtractDocument(doc, defaultOpts)
+		assert.Nil(t, err)
+		assert.NotEmpty(t, result.ContentText)
+		assert.Equal(t, original, dom.OuterHTML(doc))
+	}
+
+	// When fallback is disabled, the baseline still works on the original document
+	opts := Options{NoFallback: true}
+	str := `<html><body><div class="footer"><p>` + strings.Repeat("Some text. ", 5) + `</p></div></body></html>`
+	result, err := Extract(strings.NewReader(str), opts)
+	assert.Nil(t, err)
+	assert.Contains(t, result.ContentText, "Some text.")
 }
 
 func Test_External(t *testing.T) {
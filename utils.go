@@ -21,6 +21,8 @@ package trafilatura
 import (
 	"regexp"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 var (
@@ -34,6 +36,54 @@ func trim(s string) string {
 	return strings.TrimSpace(s)
 }
 
+// collapseSpace is like trim, except it keeps a single space at the start and end of
+// the text, so it can be joined with the adjacent inline elements.
+func collapseSpace(s string) string {
+	if s == "" {
+		return ""
+	}
+
+	collapsed := trim(s)
+	if collapsed == "" {
+		return " "
+	}
+
+	if first, _ := utf8.DecodeRuneInString(s); unicode.IsSpace(first) {
+		collapsed = " " + collapsed
+	}
+
+	if last, _ := utf8.DecodeLastRuneInString(s); unicode.IsSpace(last) {
+		collapsed += " "
+	}
+
+	return collapsed
+}
+
+// weightedLength returns the length of text that used to compare it against the size
+// thresholds. Chinese, Japanese and Korean characters are weighted using
+// CJKCharWeight in config, since each of them carries more information than a
+// Latin letter.
+func weightedLength(s string, cfg *Config) int {
+	if cfg == nil || cfg.CJKCharWeight <= 1 {
+		return utf8.RuneCountInString(s)
+	}
+
+	var length int
+	for _, r := range s {
+		if isCJK(r) {
+			length += cfg.CJKCharWeight
+		} else {
+			length++
+		}
+	}
+	return length
+}
+
+// isCJK checks if the rune is Chinese, Japanese or Korean character.
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
 func strWordCount(s string) int {
 	return len(strings.Fields(s))
 }